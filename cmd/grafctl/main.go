@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
@@ -24,6 +25,10 @@ func run(args []string) error {
 
 	if err := rootCmd.Run(context.Background()); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
+		exitErr := &command.ExitError{}
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 	return nil
@@ -1,6 +1,7 @@
 BUILD_TAGS ?=
 BIN_NAME ?= grafctl
-GO_LDFLAGS ?=
+VERSION ?= $(shell git describe --tags --always --dirty 2>/dev/null || echo dev)
+GO_LDFLAGS ?= -X github.com/diogogmt/grafctl/pkg/command.Version=$(VERSION)
 GO_GCFLAGS ?= -e
 BIN_DIR ?= ./
 define build
@@ -0,0 +1,268 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/diogogmt/grafctl/pkg/simplejson"
+)
+
+// AlertingBackup has the unified alerting resources of an organization
+type AlertingBackup struct {
+	RuleGroups    []*grafsdk.AlertRuleGroup       `json:"ruleGroups"`
+	ContactPoints []*grafsdk.ContactPoint         `json:"contactPoints"`
+	Policies      *simplejson.Json                `json:"policies,omitempty"`
+	MuteTimings   []*grafsdk.MuteTiming           `json:"muteTimings"`
+	Templates     []*grafsdk.NotificationTemplate `json:"templates"`
+}
+
+func ruleGroupKey(ruleGroup *grafsdk.AlertRuleGroup) string {
+	return "alert-rule-group/" + ruleGroup.FolderUID + "/" + ruleGroup.Title
+}
+
+func contactPointKey(contactPoint *grafsdk.ContactPoint) string {
+	return "contact-point/" + contactPoint.UID
+}
+
+func muteTimingKey(muteTiming *grafsdk.MuteTiming) string {
+	return "mute-timing/" + muteTiming.Name
+}
+
+func templateKey(template *grafsdk.NotificationTemplate) string {
+	return "template/" + template.Name
+}
+
+// redactedValue replaces the secure settings of contact points listed without decrypting them
+const redactedValue = "[REDACTED]"
+
+// contactPointRedacted reports whether a contact point has redacted secure settings, importing them would store the redacted
+// value as the secret
+func contactPointRedacted(contactPoint *grafsdk.ContactPoint) bool {
+	var walk func(node interface{}) bool
+	walk = func(node interface{}) bool {
+		switch v := node.(type) {
+		case string:
+			return v == redactedValue
+		case map[string]interface{}:
+			for _, value := range v {
+				if walk(value) {
+					return true
+				}
+			}
+		case []interface{}:
+			for _, value := range v {
+				if walk(value) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return contactPoint.Settings != nil && walk(contactPoint.Settings.Interface())
+}
+
+// redactedNames returns the sorted names of the contact points with redacted secure settings
+func redactedNames(contactPoints []*grafsdk.ContactPoint) []string {
+	names := []string{}
+	for _, contactPoint := range contactPoints {
+		if contactPointRedacted(contactPoint) {
+			names = append(names, contactPoint.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// policiesKey is the manifest key of the notification policy tree, there is a single tree per org
+const policiesKey = "notification-policies"
+
+// fetchAlerting returns the alerting resources of the client org, nil when grafana does not expose the
+// provisioning API, eg; legacy alerting or credentials without the admin role
+func (c *Client) fetchAlerting(ctx context.Context) (*AlertingBackup, error) {
+	rules, err := c.ListAlertRules(ctx)
+	if grafsdk.IsNotFound(err) {
+		c.logd("skipping alerting resources: %s", err)
+		return nil, nil
+	}
+	if grafsdk.IsUnauthorized(err) {
+		log.Printf("warning: skipping alerting resources, the provisioning API requires the admin role: %s", err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ListAlertRules: %w", err)
+	}
+
+	alerting := AlertingBackup{}
+	// the rules are listed one by one, the group interval is only returned with the rule groups
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		folderUID := rule.Get("folderUID").MustString()
+		group := rule.Get("ruleGroup").MustString()
+		if seen[folderUID+"/"+group] {
+			continue
+		}
+		seen[folderUID+"/"+group] = true
+		ruleGroup, err := c.GetAlertRuleGroup(ctx, folderUID, group)
+		if err != nil {
+			return nil, fmt.Errorf("GetAlertRuleGroup %s %s: %w", folderUID, group, err)
+		}
+		alerting.RuleGroups = append(alerting.RuleGroups, ruleGroup)
+	}
+	// grafana redacts the secure settings of the listed contact points, the export decrypts them
+	alerting.ContactPoints, err = c.ExportContactPoints(ctx)
+	if permissionsUnavailable(err) {
+		c.logd("listing the contact points, exporting them decrypted failed: %s", err)
+		if alerting.ContactPoints, err = c.ListContactPoints(ctx); err != nil {
+			return nil, fmt.Errorf("ListContactPoints: %w", err)
+		}
+		if names := redactedNames(alerting.ContactPoints); len(names) > 0 {
+			log.Printf("warning: %d contact point(s) are backed up with redacted secure settings and can't be imported, exporting them decrypted requires the admin role and grafana 9.4 or newer: %s", len(names), strings.Join(names, ", "))
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("ExportContactPoints: %w", err)
+	}
+	if alerting.Policies, err = c.GetNotificationPolicies(ctx); err != nil {
+		return nil, fmt.Errorf("GetNotificationPolicies: %w", err)
+	}
+	if alerting.MuteTimings, err = c.ListMuteTimings(ctx); err != nil {
+		return nil, fmt.Errorf("ListMuteTimings: %w", err)
+	}
+	if alerting.Templates, err = c.ListNotificationTemplates(ctx); err != nil {
+		return nil, fmt.Errorf("ListNotificationTemplates: %w", err)
+	}
+	c.logd("found %d alert rule group(s), %d contact point(s), %d mute timing(s) and %d template(s)",
+		len(alerting.RuleGroups), len(alerting.ContactPoints), len(alerting.MuteTimings), len(alerting.Templates))
+	return &alerting, nil
+}
+
+// writeAlerting writes the alerting resources as a member of the open backup object
+func writeAlerting(jsonWriter *jsonStreamWriter, section *ManifestSection, alerting *AlertingBackup) error {
+	if err := jsonWriter.key("alerting"); err != nil {
+		return err
+	}
+	if err := jsonWriter.open('{'); err != nil {
+		return err
+	}
+	if err := writeItems(jsonWriter, section, "ruleGroups", alerting.RuleGroups, ruleGroupKey); err != nil {
+		return err
+	}
+	if err := writeItems(jsonWriter, section, "contactPoints", alerting.ContactPoints, contactPointKey); err != nil {
+		return err
+	}
+	if err := writeItems(jsonWriter, section, "muteTimings", alerting.MuteTimings, muteTimingKey); err != nil {
+		return err
+	}
+	if err := writeItems(jsonWriter, section, "templates", alerting.Templates, templateKey); err != nil {
+		return err
+	}
+	if alerting.Policies != nil {
+		if err := jsonWriter.key("policies"); err != nil {
+			return err
+		}
+		sum, err := jsonWriter.checksumValue(alerting.Policies)
+		if err != nil {
+			return err
+		}
+		section.Checksums[policiesKey] = sum
+	}
+	return jsonWriter.close('}')
+}
+
+// importAlerting restores the alerting resources of a section, the templates, mute timings and contact points
+// first since the notification policies and the rules reference them.
+// folderUIDMap maps the backup folder uids to the folders of the target org.
+func (i *importer) importAlerting(ctx context.Context, alerting *AlertingBackup, folderUIDMap map[string]string) error {
+	client := i.client
+
+	for _, template := range alerting.Templates {
+		// the version of the backup would conflict with the current template
+		template.Version = ""
+		template.Provenance = ""
+		if err := client.PutNotificationTemplate(ctx, template); err != nil {
+			return fmt.Errorf("PutNotificationTemplate %s: %w", template.Name, err)
+		}
+		i.journal.notRolledBack("notification template", template.Name)
+	}
+
+	muteTimings, err := client.ListMuteTimings(ctx)
+	if err != nil {
+		return fmt.Errorf("ListMuteTimings: %w", err)
+	}
+	existingMuteTimings := map[string]bool{}
+	for _, muteTiming := range muteTimings {
+		existingMuteTimings[muteTiming.Name] = true
+	}
+	for _, muteTiming := range alerting.MuteTimings {
+		muteTiming.Version = ""
+		muteTiming.Provenance = ""
+		if existingMuteTimings[muteTiming.Name] {
+			err = client.UpdateMuteTiming(ctx, muteTiming)
+		} else {
+			err = client.CreateMuteTiming(ctx, muteTiming)
+		}
+		if err != nil {
+			return fmt.Errorf("mute timing %s: %w", muteTiming.Name, err)
+		}
+		i.journal.notRolledBack("mute timing", muteTiming.Name)
+	}
+
+	contactPoints, err := client.ListContactPoints(ctx)
+	if err != nil {
+		return fmt.Errorf("ListContactPoints: %w", err)
+	}
+	existingContactPoints := map[string]bool{}
+	for _, contactPoint := range contactPoints {
+		existingContactPoints[contactPoint.UID] = true
+	}
+	contactPointsImported := 0
+	for _, contactPoint := range alerting.ContactPoints {
+		if contactPointRedacted(contactPoint) {
+			continue
+		}
+		contactPoint.Provenance = ""
+		if existingContactPoints[contactPoint.UID] {
+			err = client.UpdateContactPoint(ctx, contactPoint)
+		} else {
+			err = client.CreateContactPoint(ctx, contactPoint)
+		}
+		if err != nil {
+			return fmt.Errorf("contact point %s %s: %w", contactPoint.UID, contactPoint.Name, err)
+		}
+		i.journal.notRolledBack("contact point", contactPoint.Name)
+		contactPointsImported++
+	}
+	if names := redactedNames(alerting.ContactPoints); len(names) > 0 {
+		log.Printf("warning: skipping %d contact point(s) whose secure settings were redacted when the backup was taken, recreate them by hand: %s", len(names), strings.Join(names, ", "))
+	}
+
+	if alerting.Policies != nil {
+		alerting.Policies.Del("provenance")
+		if err := client.SetNotificationPolicies(ctx, alerting.Policies); err != nil {
+			return fmt.Errorf("SetNotificationPolicies: %w", err)
+		}
+		i.journal.notRolledBack("notification policies", policiesKey)
+	}
+
+	// the rule groups are restored into the folders with the same title, their uid can differ
+	for _, ruleGroup := range alerting.RuleGroups {
+		if folderUID, ok := folderUIDMap[ruleGroup.FolderUID]; ok {
+			ruleGroup.FolderUID = folderUID
+		}
+		for _, rule := range ruleGroup.Rules {
+			rule.Del("id")
+			rule.Del("provenance")
+			rule.Set("folderUID", ruleGroup.FolderUID)
+		}
+		if err := client.PutAlertRuleGroup(ctx, ruleGroup); err != nil {
+			return fmt.Errorf("PutAlertRuleGroup %s %s: %w", ruleGroup.FolderUID, ruleGroup.Title, err)
+		}
+		i.journal.notRolledBack("alert rule group", ruleGroup.Title)
+	}
+	i.conf.logd("imported %d alert rule group(s), %d contact point(s), %d mute timing(s) and %d template(s)",
+		len(alerting.RuleGroups), contactPointsImported, len(alerting.MuteTimings), len(alerting.Templates))
+	return nil
+}
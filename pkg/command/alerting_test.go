@@ -0,0 +1,107 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/diogogmt/grafctl/pkg/simplejson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportAlerting(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	alerting := sourceOrg.enableAlerting()
+	sourceOrg.addAlertRuleGroup(sourceOrg.addFolder("alerts-folder", "Alerts"), "cpu", "rule-1", "rule-2")
+	settings := simplejson.New()
+	settings.Set("addresses", "oncall@example.com")
+	alerting.contactPoints = []*grafsdk.ContactPoint{{UID: "oncall-uid", Name: "oncall", Type: "email", Settings: settings}}
+	alerting.muteTimings = []*grafsdk.MuteTiming{{Name: "weekends", TimeIntervals: simplejson.NewFromAny([]interface{}{}), Version: "abc"}}
+	alerting.templates = []*grafsdk.NotificationTemplate{{Name: "oncall", Template: `{{ define "oncall" }}page{{ end }}`}}
+	alerting.policies.Set("receiver", "oncall")
+	src := backupFile(t, source, BackupOptions{})
+
+	var buf bytes.Buffer
+	verifier := backupVerifier{}
+	manifest, err := (&RootConfig{}).readBackup(context.Background(), src, &verifier)
+	assert.NoError(t, err)
+	assert.NoError(t, verifier.report(&buf, manifest))
+	assert.Contains(t, manifest.Sections[0].Checksums, "alert-rule-group/alerts-folder/cpu")
+	assert.Contains(t, manifest.Sections[0].Checksums, policiesKey)
+
+	// the target folder has the same title but another uid and the contact point already exists
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetAlerting := targetOrg.enableAlerting()
+	targetOrg.addFolder("other-uid", "Alerts")
+	targetAlerting.contactPoints = []*grafsdk.ContactPoint{{UID: "oncall-uid", Name: "old", Type: "email"}}
+	targetAlerting.muteTimings = []*grafsdk.MuteTiming{{Name: "weekends", Version: "def"}}
+	assert.NoError(t, runImport(t, target, "-src", src))
+
+	assert.Len(t, targetAlerting.ruleGroups, 1)
+	ruleGroup := targetAlerting.ruleGroups[0]
+	assert.Equal(t, "other-uid", ruleGroup.FolderUID)
+	assert.Equal(t, "cpu", ruleGroup.Title)
+	assert.Equal(t, int64(60), ruleGroup.Interval)
+	assert.Len(t, ruleGroup.Rules, 2)
+	for _, rule := range ruleGroup.Rules {
+		assert.Equal(t, "other-uid", rule.Get("folderUID").MustString())
+	}
+	assert.Len(t, targetAlerting.contactPoints, 1)
+	assert.Equal(t, "oncall", targetAlerting.contactPoints[0].Name)
+	assert.Equal(t, "oncall@example.com", targetAlerting.contactPoints[0].Settings.Get("addresses").MustString())
+	assert.Len(t, targetAlerting.muteTimings, 1)
+	assert.Empty(t, targetAlerting.muteTimings[0].Version)
+	assert.Len(t, targetAlerting.templates, 1)
+	assert.Equal(t, "oncall", targetAlerting.policies.Get("receiver").MustString())
+}
+
+func TestBackupSkipsForbiddenAlerting(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	grafana.orgs[1].enableAlerting()
+	grafana.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.HasPrefix(r.URL.Path, "/api/v1/provisioning/") {
+			return false
+		}
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": "permissions needed"})
+		return true
+	}
+	dir := t.TempDir()
+	assert.NoError(t, grafana.client().BackupGrafana(context.Background(), BackupOptions{Store: NewLocalBackupStore(dir)}))
+	assert.Nil(t, readLocalBackup(t, dir).Alerting)
+}
+
+func TestImportContactPointSecrets(t *testing.T) {
+	for _, decrypt := range []bool{true, false} {
+		source := newFakeGrafana(t)
+		alerting := source.orgs[1].enableAlerting()
+		alerting.decrypt = decrypt
+		webhook := simplejson.New()
+		webhook.Set("url", "https://hooks.example.com/s3cr3t")
+		email := simplejson.New()
+		email.Set("addresses", "oncall@example.com")
+		alerting.contactPoints = []*grafsdk.ContactPoint{
+			{UID: "webhook-uid", Name: "webhook", Type: "webhook", Settings: webhook},
+			{UID: "email-uid", Name: "email", Type: "email", Settings: email},
+		}
+		src := backupFile(t, source, BackupOptions{})
+
+		target := newFakeGrafana(t)
+		targetAlerting := target.orgs[1].enableAlerting()
+		assert.NoError(t, runImport(t, target, "-src", src))
+
+		if decrypt {
+			// the export has the decrypted secure settings
+			assert.Len(t, targetAlerting.contactPoints, 2)
+			assert.Equal(t, "https://hooks.example.com/s3cr3t", targetAlerting.contactPoints[0].Settings.Get("url").MustString())
+			continue
+		}
+		// contact points with redacted secure settings are skipped rather than stored with the redacted value
+		assert.Len(t, targetAlerting.contactPoints, 1)
+		assert.Equal(t, "email", targetAlerting.contactPoints[0].Name)
+	}
+}
@@ -0,0 +1,370 @@
+package command
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+)
+
+// backupNameRegexp matches the names of the backup archives, eg; grafana_example_com-2020-12-20-1608422400000000000.json.gz
+var backupNameRegexp = regexp.MustCompile(`^(.+)-(\d{4}-\d{2}-\d{2})-(\d+)\.json\.gz(\.enc)?$`)
+
+// encryptedArchiveSuffix is appended to the name of encrypted archives
+const encryptedArchiveSuffix = ".enc"
+
+// BackupArchive is a backup archive of a store with the host and creation time parsed from its name
+type BackupArchive struct {
+	*BackupObject
+	Host    string
+	Created time.Time
+}
+
+// backupHost returns the host as written in the backup archive names
+func backupHost(host string) string {
+	return strings.ReplaceAll(host, ".", "_")
+}
+
+// formatBackupName returns the name of the archive of a backup of host taken at t
+func formatBackupName(host string, t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%s-%s-%d.json.gz", host, t.Format("2006-01-02"), t.UnixNano())
+}
+
+// parseBackupName returns the host and creation time of a backup archive name
+func parseBackupName(name string) (string, time.Time, bool) {
+	matches := backupNameRegexp.FindStringSubmatch(name)
+	if matches == nil {
+		return "", time.Time{}, false
+	}
+	nanos, err := strconv.ParseInt(matches[3], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return matches[1], time.Unix(0, nanos).UTC(), true
+}
+
+// listBackupArchives returns the backup archives of the store sorted from newest to oldest.
+// Objects not following the backup naming scheme are ignored, an empty host lists the archives of every host.
+func listBackupArchives(ctx context.Context, store BackupStore, host string) ([]*BackupArchive, error) {
+	objects, err := store.List(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	archives := []*BackupArchive{}
+	for _, object := range objects {
+		archiveHost, created, ok := parseBackupName(object.Name)
+		if !ok || (host != "" && archiveHost != host) {
+			continue
+		}
+		archives = append(archives, &BackupArchive{BackupObject: object, Host: archiveHost, Created: created})
+	}
+	sort.SliceStable(archives, func(i, j int) bool { return archives[i].Created.After(archives[j].Created) })
+	return archives, nil
+}
+
+// jsonStreamWriter writes a JSON document incrementally so large arrays never have to be held in memory
+type jsonStreamWriter struct {
+	w io.Writer
+	// first tracks, for every open object or array, whether the next member is the first one
+	first []bool
+	// afterKey is set when an object key was written and its value is pending
+	afterKey bool
+}
+
+func newJSONStreamWriter(w io.Writer) *jsonStreamWriter {
+	return &jsonStreamWriter{w: w}
+}
+
+// separate writes the comma between the members of an object or array
+func (s *jsonStreamWriter) separate() error {
+	if s.afterKey {
+		s.afterKey = false
+		return nil
+	}
+	if len(s.first) == 0 {
+		return nil
+	}
+	if s.first[len(s.first)-1] {
+		s.first[len(s.first)-1] = false
+		return nil
+	}
+	_, err := io.WriteString(s.w, ",")
+	return err
+}
+
+// open starts an object or array, delim is either '{' or '['
+func (s *jsonStreamWriter) open(delim byte) error {
+	if err := s.separate(); err != nil {
+		return err
+	}
+	s.first = append(s.first, true)
+	_, err := s.w.Write([]byte{delim})
+	return err
+}
+
+// close ends the innermost object or array, delim is either '}' or ']'
+func (s *jsonStreamWriter) close(delim byte) error {
+	if len(s.first) == 0 {
+		return fmt.Errorf("close %q: no open object or array", delim)
+	}
+	s.first = s.first[:len(s.first)-1]
+	_, err := s.w.Write([]byte{delim})
+	return err
+}
+
+// key writes an object key, it must be followed by a value or a call to open
+func (s *jsonStreamWriter) key(name string) error {
+	if err := s.separate(); err != nil {
+		return err
+	}
+	by, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(append(by, ':')); err != nil {
+		return err
+	}
+	s.afterKey = true
+	return nil
+}
+
+// value writes v as a complete JSON value
+func (s *jsonStreamWriter) value(v interface{}) error {
+	by, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := s.separate(); err != nil {
+		return err
+	}
+	_, err = s.w.Write(by)
+	return err
+}
+
+// checksumValue writes v like value and returns the checksum of its JSON encoding
+func (s *jsonStreamWriter) checksumValue(v interface{}) (string, error) {
+	by, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if err := s.separate(); err != nil {
+		return "", err
+	}
+	if _, err := s.w.Write(by); err != nil {
+		return "", err
+	}
+	return checksum(by), nil
+}
+
+// writeItems writes the items as an array member of the open object and records their checksums in the manifest section
+func writeItems[T any](s *jsonStreamWriter, section *ManifestSection, name string, items []T, itemKey func(T) string) error {
+	if err := s.key(name); err != nil {
+		return err
+	}
+	if err := s.open('['); err != nil {
+		return err
+	}
+	for _, item := range items {
+		sum, err := s.checksumValue(item)
+		if err != nil {
+			return err
+		}
+		section.Checksums[itemKey(item)] = sum
+	}
+	return s.close(']')
+}
+
+// field writes an object key and its value
+func (s *jsonStreamWriter) field(name string, v interface{}) error {
+	if err := s.key(name); err != nil {
+		return err
+	}
+	return s.value(v)
+}
+
+// backupVisitor receives the sections of a backup archive as they are decoded.
+// begin is called with everything but the dashboards of a section, the dashboards are then passed one at a time,
+// and end is called with the members that followed the dashboards in the archive.
+// The org is nil for the top level section of a single org backup.
+type backupVisitor interface {
+	begin(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error
+	dashboard(ctx context.Context, dashboard *grafsdk.DashboardWithMeta) error
+	end(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error
+}
+
+// readBackup streams the backup archive at src, a local path or a remote object URL, to the visitor.
+// It returns the manifest of the archive, nil for archives written before the manifest.
+func (c *RootConfig) readBackup(ctx context.Context, src string, visitor backupVisitor) (*BackupManifest, error) {
+	provider, location, name := splitBackupSrc(src)
+	store, err := c.BackupStore(ctx, provider, location)
+	if err != nil {
+		return nil, err
+	}
+	backupReader, err := store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer backupReader.Close()
+
+	encryptionKey, err := c.encryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	archiveReader, err := openArchive(backupReader, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	gzipReader, err := gzip.NewReader(archiveReader)
+	if err != nil {
+		return nil, fmt.Errorf("gzip.NewReader: %w", err)
+	}
+	defer gzipReader.Close()
+
+	manifest, err := decodeBackup(ctx, gzipReader, visitor)
+	if err != nil {
+		return nil, err
+	}
+	if err := gzipReader.Close(); err != nil {
+		return nil, fmt.Errorf("zr.Close: %w", err)
+	}
+	return manifest, nil
+}
+
+// backupDecoder streams a backup archive to a visitor
+type backupDecoder struct {
+	dec      *json.Decoder
+	visitor  backupVisitor
+	manifest *BackupManifest
+}
+
+// decodeBackup streams a backup archive to the visitor without reading the whole archive in memory.
+// It returns the manifest of the archive, nil for archives written before the manifest.
+func decodeBackup(ctx context.Context, r io.Reader, visitor backupVisitor) (*BackupManifest, error) {
+	d := backupDecoder{dec: json.NewDecoder(r), visitor: visitor}
+	if err := d.section(ctx, true); err != nil {
+		return nil, err
+	}
+	if _, err := d.dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the backup")
+	}
+	return d.manifest, nil
+}
+
+// section decodes a backup object, the top level one may contain the org sections and the manifest
+func (d *backupDecoder) section(ctx context.Context, topLevel bool) error {
+	dec := d.dec
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	var org *grafsdk.Org
+	grafanaBackup := GrafanaBackup{}
+	fields := grafanaBackup.fields()
+	begun := false
+	hasOrgs := false
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("json.Token: %w", err)
+		}
+		key, _ := token.(string)
+		switch {
+		case key == "org" && !topLevel:
+			if err := dec.Decode(&org); err != nil {
+				return fmt.Errorf("decode org: %w", err)
+			}
+		case key == "orgs" && topLevel:
+			hasOrgs = true
+			if err := decodeArray(dec, func() error {
+				return d.section(ctx, false)
+			}); err != nil {
+				return fmt.Errorf("orgs: %w", err)
+			}
+		case key == "manifest" && topLevel:
+			if err := dec.Decode(&d.manifest); err != nil {
+				return fmt.Errorf("decode manifest: %w", err)
+			}
+		case key == "dashboards":
+			if err := decodeArray(dec, func() error {
+				if !begun {
+					begun = true
+					if err := d.visitor.begin(ctx, org, &grafanaBackup); err != nil {
+						return err
+					}
+				}
+				dashboard := grafsdk.DashboardWithMeta{}
+				if err := dec.Decode(&dashboard); err != nil {
+					return fmt.Errorf("decode dashboard: %w", err)
+				}
+				return d.visitor.dashboard(ctx, &dashboard)
+			}); err != nil {
+				return fmt.Errorf("dashboards: %w", err)
+			}
+		case fields[key] != nil:
+			if err := dec.Decode(fields[key]); err != nil {
+				return fmt.Errorf("decode %s: %w", key, err)
+			}
+		default:
+			// skip members written by newer versions
+			if err := dec.Decode(&json.RawMessage{}); err != nil {
+				return fmt.Errorf("decode %s: %w", key, err)
+			}
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return err
+	}
+
+	// the top level section of a backup with org sections only holds the orgs, other sections are visited even when
+	// empty since the manifest has them, eg; the backup of an empty grafana
+	if !begun && (!topLevel || !hasOrgs || !grafanaBackup.empty()) {
+		if err := d.visitor.begin(ctx, org, &grafanaBackup); err != nil {
+			return err
+		}
+		begun = true
+	}
+	if !begun {
+		return nil
+	}
+	return d.visitor.end(ctx, org, &grafanaBackup)
+}
+
+// decodeArray calls decodeElem for every element of an array, a null array has no elements
+func decodeArray(dec *json.Decoder, decodeElem func() error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("json.Token: %w", err)
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected array, got %v", token)
+	}
+	for dec.More() {
+		if err := decodeElem(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, expected json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("json.Token: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("expected %q, got %v", expected, token)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingVisitor records the backup sections and dashboards passed to a backupVisitor
+type recordingVisitor struct {
+	events []string
+}
+
+func (v *recordingVisitor) begin(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	name := ""
+	if org != nil {
+		name = org.Name
+	}
+	v.events = append(v.events, "begin:"+name)
+	for _, datasource := range grafanaBackup.Datasources {
+		v.events = append(v.events, "datasource:"+datasource.Name)
+	}
+	for _, folder := range grafanaBackup.Folders {
+		v.events = append(v.events, "folder:"+folder.Title)
+	}
+	return nil
+}
+
+func (v *recordingVisitor) dashboard(ctx context.Context, dashboard *grafsdk.DashboardWithMeta) error {
+	v.events = append(v.events, "dashboard:"+dashboard.Dashboard.Get("uid").MustString())
+	return nil
+}
+
+func (v *recordingVisitor) end(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	v.events = append(v.events, "end")
+	return nil
+}
+
+func gunzip(t *testing.T, r io.Reader) io.Reader {
+	gzipReader, err := gzip.NewReader(r)
+	assert.NoError(t, err)
+	return gzipReader
+}
+
+func TestJSONStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newJSONStreamWriter(&buf)
+	assert.NoError(t, w.open('{'))
+	assert.NoError(t, w.field("a", 1))
+	assert.NoError(t, w.key("b"))
+	assert.NoError(t, w.open('['))
+	assert.NoError(t, w.value("x"))
+	assert.NoError(t, w.open('{'))
+	assert.NoError(t, w.close('}'))
+	assert.NoError(t, w.open('['))
+	assert.NoError(t, w.close(']'))
+	assert.NoError(t, w.close(']'))
+	assert.NoError(t, w.field("c", map[string]bool{"d": true}))
+	assert.NoError(t, w.close('}'))
+	assert.Error(t, w.close('}'))
+
+	assert.Equal(t, `{"a":1,"b":["x",{},[]],"c":{"d":true}}`, buf.String())
+	assert.True(t, json.Valid(buf.Bytes()))
+}
+
+func TestDecodeBackup(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	mainOrg := grafana.orgs[1]
+	mainOrg.addDatasource("prometheus", "prometheus")
+	folder := mainOrg.addFolder("folder", "Folder")
+	mainOrg.addDashboard("dash-1", "Dash 1", folder)
+	mainOrg.addDashboard("dash-2", "Dash 2", nil)
+	teamOrg := grafana.addOrg(2, "Team")
+	teamOrg.addDashboard("team-dash", "Team", nil)
+	grafana.addOrg(3, "Empty")
+
+	ctx := context.Background()
+	client := grafana.client()
+
+	// streamed archive of a single org
+	var buf bytes.Buffer
+	assert.NoError(t, client.writeBackup(ctx, BackupOptions{}, &buf))
+	visitor := recordingVisitor{}
+	manifest, err := decodeBackup(ctx, gunzip(t, &buf), &visitor)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"begin:", "datasource:prometheus", "folder:Folder", "dashboard:dash-1", "dashboard:dash-2", "end"}, visitor.events)
+	if assert.NotNil(t, manifest) {
+		assert.Equal(t, backupFormatVersion, manifest.FormatVersion)
+		assert.Equal(t, grafana.URL, manifest.Source)
+		assert.Equal(t, "9.5.0", manifest.Grafana.Version)
+		assert.Len(t, manifest.Sections, 1)
+		assert.Equal(t, "Main Org.", manifest.Sections[0].Org.Name)
+		assert.Len(t, manifest.Sections[0].Checksums, 4)
+	}
+
+	// streamed archive with org sections, sections without dashboards are still visited
+	buf.Reset()
+	assert.NoError(t, client.writeBackup(ctx, BackupOptions{AllOrgs: true}, &buf))
+	visitor = recordingVisitor{}
+	_, err = decodeBackup(ctx, gunzip(t, &buf), &visitor)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"begin:Main Org.", "datasource:prometheus", "folder:Folder", "dashboard:dash-1", "dashboard:dash-2", "end",
+		"begin:Team", "dashboard:team-dash", "end",
+		"begin:Empty", "end",
+	}, visitor.events)
+
+	// archives written before backups were streamed hold the whole document with null members
+	legacyBy, err := json.Marshal(GrafanaBackup{Orgs: []*GrafanaOrgBackup{{
+		Org:           &grafsdk.Org{ID: 2, Name: "Team"},
+		GrafanaBackup: GrafanaBackup{Dashboards: teamOrg.dashboards},
+	}}})
+	assert.NoError(t, err)
+	visitor = recordingVisitor{}
+	_, err = decodeBackup(ctx, bytes.NewReader(legacyBy), &visitor)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"begin:Team", "dashboard:team-dash", "end"}, visitor.events)
+
+	// unknown members are skipped
+	visitor = recordingVisitor{}
+	_, err = decodeBackup(ctx, strings.NewReader(`{"version":{"a":[1,2]},"folders":[{"title":"Folder"}],"dashboards":[]}`), &visitor)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"begin:", "folder:Folder", "end"}, visitor.events)
+
+	// truncated archives fail
+	_, err = decodeBackup(ctx, strings.NewReader(`{"dashboards":[{"dashboard":{}}`), &recordingVisitor{})
+	assert.Error(t, err)
+	_, err = decodeBackup(ctx, strings.NewReader(`{"dashboards":{}}`), &recordingVisitor{})
+	assert.Error(t, err)
+}
@@ -11,8 +11,11 @@ import (
 type BackupConfig struct {
 	*RootConfig
 
-	Provider string
-	Out      string
+	Provider    string
+	Out         string
+	AllOrgs     bool
+	Include     string
+	Concurrency int
 }
 
 // BackupCmd wraps the dashboardBackup config and a ffcli.Command
@@ -34,26 +37,75 @@ func NewBackupCmd(rootConf *RootConfig) *BackupCmd {
 	cmd.RegisterFlags(fs)
 
 	cmd.Command = &ffcli.Command{
-		Name:        "backup",
-		ShortUsage:  "grafctl backup",
-		ShortHelp:   "Backup grafana dashboards and datasources",
-		FlagSet:     fs,
-		Exec:        cmd.Exec,
-		Subcommands: []*ffcli.Command{},
+		Name:       "backup",
+		ShortUsage: "grafctl backup",
+		ShortHelp:  "Backup grafana dashboards and datasources",
+		FlagSet:    fs,
+		Exec:       cmd.Exec,
+		Subcommands: []*ffcli.Command{
+			NewBackupLsCmd(&conf).Command,
+			NewBackupShowCmd(&conf).Command,
+			NewBackupVerifyCmd(&conf).Command,
+			NewBackupPruneCmd(&conf).Command,
+		},
 	}
 	return &cmd
 }
 
 // RegisterFlags registers a set of flags for the dashboardBackup command
 func (c *BackupCmd) RegisterFlags(fs *flag.FlagSet) {
-	fs.StringVar(&c.Conf.Provider, "provider", "local", "object storage provider, eg; local/gcs")
-	fs.StringVar(&c.Conf.Out, "out", "", "location where to store the backup; either the path to a local dir or the remote bucket")
+	c.Conf.registerLocationFlags(fs)
+	fs.IntVar(&c.Conf.Concurrency, "concurrency", 8, "number of dashboards fetched in parallel")
+	fs.BoolVar(&c.Conf.AllOrgs, "all-orgs", false, "backup every organization visible to the credentials, requires server admin permissions")
+	fs.StringVar(&c.Conf.Include, "include", "", "comma separated opt-in sections, eg; users,teams; requires org admin permissions")
+}
+
+// registerLocationFlags registers the flags of the backup location, the backup subcommands share them with backup
+func (c *BackupConfig) registerLocationFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Provider, "provider", "local", "object storage provider, eg; local/gcs/s3")
+	fs.StringVar(&c.Out, "out", "", "location where to store the backup; either the path to a local dir or the remote bucket, eg; s3://grafana-backup-bucket/prefix")
+}
+
+// store returns the store of the backup location
+func (c *BackupConfig) store(ctx context.Context) (BackupStore, error) {
+	return c.BackupStore(ctx, BackupProvider(c.Provider), c.Out)
 }
 
 // Exec executes the dashboardBackup command
 func (c *BackupCmd) Exec(ctx context.Context, args []string) error {
-	if err := c.Conf.Client().BackupGrafana(ctx, BackupProvider(c.Conf.Provider), c.Conf.Out); err != nil {
+	includeUsers, includeTeams, err := parseBackupIncludes(c.Conf.Include)
+	if err != nil {
+		return err
+	}
+	client, err := c.Conf.Client(ctx)
+	if err != nil {
+		return err
+	}
+	store, err := c.Conf.store(ctx)
+	if err != nil {
+		return err
+	}
+	encryptionKey, err := c.Conf.encryptionKey()
+	if err != nil {
+		return err
+	}
+	if err := client.BackupGrafana(ctx, BackupOptions{
+		Store:         store,
+		EncryptionKey: encryptionKey,
+		AllOrgs:       c.Conf.AllOrgs,
+		IncludeUsers:  includeUsers,
+		IncludeTeams:  includeTeams,
+		Concurrency:   c.Conf.Concurrency,
+		Progress:      c.progress,
+	}); err != nil {
 		return err
 	}
 	return nil
 }
+
+// progress logs the number of dashboards fetched every 100 dashboards and once all are fetched
+func (c *BackupCmd) progress(done int, total int) {
+	if done%100 == 0 || done == total {
+		c.Conf.logd("fetched %d/%d dashboards", done, total)
+	}
+}
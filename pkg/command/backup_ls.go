@@ -0,0 +1,99 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/peterbourgon/ff/v2/ffcli"
+)
+
+// BackupLsConfig has the config for the backupLs command and a reference to the backup command config
+type BackupLsConfig struct {
+	*BackupConfig
+
+	Host string
+}
+
+// BackupLsCmd wraps the backupLs config and a ffcli.Command
+type BackupLsCmd struct {
+	Conf *BackupLsConfig
+
+	*ffcli.Command
+}
+
+// NewBackupLsCmd creates a new BackupLsCmd
+func NewBackupLsCmd(backupConf *BackupConfig) *BackupLsCmd {
+	conf := BackupLsConfig{
+		BackupConfig: backupConf,
+	}
+	cmd := BackupLsCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl backup ls", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:        "ls",
+		ShortUsage:  "grafctl backup ls",
+		ShortHelp:   "List the backups of a local dir or bucket",
+		FlagSet:     fs,
+		Exec:        cmd.Exec,
+		Subcommands: []*ffcli.Command{},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the backupLs command
+func (c *BackupLsCmd) RegisterFlags(fs *flag.FlagSet) {
+	c.Conf.registerLocationFlags(fs)
+	fs.StringVar(&c.Conf.Host, "host", "", "only list the backups of the grafana host, eg; grafana.example.com; defaults to the host of -url")
+}
+
+// Exec executes the backupLs command
+func (c *BackupLsCmd) Exec(ctx context.Context, args []string) error {
+	host := c.Conf.Host
+	if host == "" && c.Conf.APIURL != "" {
+		u, err := url.Parse(c.Conf.APIURL)
+		if err != nil {
+			return err
+		}
+		host = u.Host
+	}
+
+	store, err := c.Conf.store(ctx)
+	if err != nil {
+		return err
+	}
+	archives, err := listBackupArchives(ctx, store, backupHost(host))
+	if err != nil {
+		return fmt.Errorf("listBackupArchives: %w", err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Host", "Created", "Size"})
+	for _, archive := range archives {
+		table.Append([]string{archive.Name, archive.Host, archive.Created.Format(time.RFC3339), formatSize(archive.Size)})
+	}
+	table.Render()
+
+	return nil
+}
+
+// formatSize formats a number of bytes with a binary unit, eg; 1.5 MiB
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListBackupArchives(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2020, 12, 20, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{
+		formatBackupName("grafana_example_com", now.Add(-time.Hour)),
+		formatBackupName("grafana_example_com", now),
+		formatBackupName("localhost:3000", now),
+		"grafana_example_com-notes.txt",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("backup"), 0644))
+	}
+
+	archives, err := listBackupArchives(context.Background(), NewLocalBackupStore(dir), backupHost("grafana.example.com"))
+	assert.NoError(t, err)
+	if assert.Len(t, archives, 2) {
+		assert.Equal(t, "grafana_example_com", archives[0].Host)
+		assert.Equal(t, now, archives[0].Created)
+		assert.Equal(t, now.Add(-time.Hour), archives[1].Created)
+		assert.Equal(t, int64(6), archives[0].Size)
+	}
+
+	archives, err = listBackupArchives(context.Background(), NewLocalBackupStore(dir), "")
+	assert.NoError(t, err)
+	assert.Len(t, archives, 3)
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "2.0 MiB", formatSize(2*1024*1024))
+}
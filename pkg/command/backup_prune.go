@@ -0,0 +1,145 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/peterbourgon/ff/v2/ffcli"
+)
+
+// BackupPruneConfig has the config for the backupPrune command and a reference to the backup command config
+type BackupPruneConfig struct {
+	*BackupConfig
+
+	RetentionPolicy
+	DryRun bool
+}
+
+// BackupPruneCmd wraps the backupPrune config and a ffcli.Command
+type BackupPruneCmd struct {
+	Conf *BackupPruneConfig
+
+	*ffcli.Command
+}
+
+// NewBackupPruneCmd creates a new BackupPruneCmd
+func NewBackupPruneCmd(backupConf *BackupConfig) *BackupPruneCmd {
+	conf := BackupPruneConfig{
+		BackupConfig: backupConf,
+	}
+	cmd := BackupPruneCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl backup prune", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:        "prune",
+		ShortUsage:  "grafctl backup prune",
+		ShortHelp:   "Remove the backups outside of the retention policy",
+		FlagSet:     fs,
+		Exec:        cmd.Exec,
+		Subcommands: []*ffcli.Command{},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the backupPrune command
+func (c *BackupPruneCmd) RegisterFlags(fs *flag.FlagSet) {
+	c.Conf.registerLocationFlags(fs)
+	fs.IntVar(&c.Conf.KeepLast, "keep-last", 0, "keep the n most recent backups")
+	fs.IntVar(&c.Conf.KeepDaily, "keep-daily", 0, "keep the most recent backup of the last n days with backups")
+	fs.IntVar(&c.Conf.KeepWeekly, "keep-weekly", 0, "keep the most recent backup of the last n weeks with backups")
+	fs.DurationVar(&c.Conf.MaxAge, "max-age", 0, "keep the backups taken within the duration, eg; 720h")
+	fs.BoolVar(&c.Conf.DryRun, "dry-run", false, "print the backups that would be removed without removing them")
+}
+
+// Exec executes the backupPrune command
+func (c *BackupPruneCmd) Exec(ctx context.Context, args []string) error {
+	if c.Conf.RetentionPolicy.empty() {
+		return fmt.Errorf("missing retention policy, set at least one of -keep-last, -keep-daily, -keep-weekly or -max-age")
+	}
+	store, err := c.Conf.store(ctx)
+	if err != nil {
+		return err
+	}
+	archives, err := listBackupArchives(ctx, store, "")
+	if err != nil {
+		return fmt.Errorf("listBackupArchives: %w", err)
+	}
+
+	// the policy applies to the backups of every host separately
+	hostArchives := map[string][]*BackupArchive{}
+	hosts := []string{}
+	for _, archive := range archives {
+		if _, ok := hostArchives[archive.Host]; !ok {
+			hosts = append(hosts, archive.Host)
+		}
+		hostArchives[archive.Host] = append(hostArchives[archive.Host], archive)
+	}
+
+	now := time.Now()
+	for _, host := range hosts {
+		_, remove := c.Conf.RetentionPolicy.apply(hostArchives[host], now)
+		c.Conf.logd("host %s: keeping %d of %d backup(s)", host, len(hostArchives[host])-len(remove), len(hostArchives[host]))
+		for _, archive := range remove {
+			if c.Conf.DryRun {
+				fmt.Printf("would remove %s\n", archive.Name)
+				continue
+			}
+			if err := store.Delete(ctx, archive.Name); err != nil {
+				return fmt.Errorf("Delete %s: %w", archive.Name, err)
+			}
+			fmt.Printf("removed %s\n", archive.Name)
+		}
+	}
+	return nil
+}
+
+// RetentionPolicy selects the backups to keep, a backup is kept when any of the rules selects it
+type RetentionPolicy struct {
+	KeepLast   int
+	KeepDaily  int
+	KeepWeekly int
+	MaxAge     time.Duration
+}
+
+func (p RetentionPolicy) empty() bool {
+	return p.KeepLast <= 0 && p.KeepDaily <= 0 && p.KeepWeekly <= 0 && p.MaxAge <= 0
+}
+
+// apply splits the archives of a single host, sorted from newest to oldest, into the ones to keep and the ones to remove.
+// Days and weeks are UTC like the dates of the archive names, and the most recent backup is always kept.
+func (p RetentionPolicy) apply(archives []*BackupArchive, now time.Time) ([]*BackupArchive, []*BackupArchive) {
+	keep, remove := []*BackupArchive{}, []*BackupArchive{}
+	days, weeks := map[string]bool{}, map[string]bool{}
+	for i, archive := range archives {
+		created := archive.Created.UTC()
+		day := created.Format("2006-01-02")
+		year, week := created.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%d", year, week)
+
+		kept := i == 0 || i < p.KeepLast
+		if p.MaxAge > 0 && now.Sub(archive.Created) <= p.MaxAge {
+			kept = true
+		}
+		// the first archive seen for a day or week is its most recent one
+		if !days[day] && len(days) < p.KeepDaily {
+			days[day] = true
+			kept = true
+		}
+		if !weeks[weekKey] && len(weeks) < p.KeepWeekly {
+			weeks[weekKey] = true
+			kept = true
+		}
+
+		if kept {
+			keep = append(keep, archive)
+		} else {
+			remove = append(remove, archive)
+		}
+	}
+	return keep, remove
+}
@@ -0,0 +1,93 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	now := time.Date(2020, 12, 20, 12, 0, 0, 0, time.UTC)
+	// two backups a day for the last 30 days, newest first
+	archives := []*BackupArchive{}
+	for i := 0; i < 60; i++ {
+		created := now.Add(-time.Duration(i) * 12 * time.Hour)
+		archives = append(archives, &BackupArchive{BackupObject: &BackupObject{Name: formatBackupName("grafana", created)}, Created: created})
+	}
+	names := func(archives []*BackupArchive) []string {
+		names := []string{}
+		for _, archive := range archives {
+			names = append(names, archive.Name)
+		}
+		return names
+	}
+
+	keep, remove := RetentionPolicy{KeepLast: 3}.apply(archives, now)
+	assert.Equal(t, names(archives[:3]), names(keep))
+	assert.Len(t, remove, 57)
+
+	// the noon backup of each day
+	keep, _ = RetentionPolicy{KeepDaily: 3}.apply(archives, now)
+	assert.Equal(t, names([]*BackupArchive{archives[0], archives[2], archives[4]}), names(keep))
+
+	// 2020-12-20 is a sunday, the previous week ends with the noon backup of 2020-12-13
+	keep, _ = RetentionPolicy{KeepWeekly: 2}.apply(archives, now)
+	assert.Equal(t, names([]*BackupArchive{archives[0], archives[14]}), names(keep))
+
+	keep, _ = RetentionPolicy{MaxAge: 36 * time.Hour}.apply(archives, now)
+	assert.Equal(t, names(archives[:4]), names(keep))
+
+	// rules are combined
+	keep, _ = RetentionPolicy{KeepLast: 2, KeepDaily: 3}.apply(archives, now)
+	assert.Equal(t, names([]*BackupArchive{archives[0], archives[1], archives[2], archives[4]}), names(keep))
+
+	// the most recent backup is never removed
+	keep, _ = RetentionPolicy{MaxAge: time.Hour}.apply(archives[10:], now)
+	assert.Equal(t, names(archives[10:11]), names(keep))
+}
+
+func TestBackupPruneCmd(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := []string{
+		formatBackupName("grafana_example_com", now),
+		formatBackupName("grafana_example_com", now.Add(-time.Hour)),
+		formatBackupName("grafana_example_com", now.Add(-2*time.Hour)),
+		formatBackupName("other_example_com", now.Add(-3*time.Hour)),
+		formatBackupName("other_example_com", now.Add(-4*time.Hour)),
+		"notes.txt",
+	}
+	for _, f := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, f), []byte("backup"), 0644))
+	}
+	runPrune := func(args ...string) error {
+		rootCmd := NewRootCmd()
+		if err := rootCmd.Parse(append([]string{"-config", filepath.Join(t.TempDir(), "config.yaml"), "backup", "prune", "-out", dir}, args...)); err != nil {
+			return err
+		}
+		return rootCmd.Run(context.Background())
+	}
+	ls := func() []string {
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		names := []string{}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	// a policy is required
+	assert.Error(t, runPrune())
+
+	assert.NoError(t, runPrune("-keep-last", "1", "-dry-run"))
+	assert.Len(t, ls(), 6)
+
+	// the policy applies to every host separately and unknown files are left alone
+	assert.NoError(t, runPrune("-keep-last", "1"))
+	assert.ElementsMatch(t, []string{files[0], files[3], "notes.txt"}, ls())
+}
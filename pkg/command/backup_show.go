@@ -0,0 +1,178 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/olekukonko/tablewriter"
+	"github.com/peterbourgon/ff/v2/ffcli"
+)
+
+// BackupShowConfig has the config for the backupShow command and a reference to the backup command config
+type BackupShowConfig struct {
+	*BackupConfig
+}
+
+// BackupShowCmd wraps the backupShow config and a ffcli.Command
+type BackupShowCmd struct {
+	Conf *BackupShowConfig
+
+	*ffcli.Command
+}
+
+// NewBackupShowCmd creates a new BackupShowCmd
+func NewBackupShowCmd(backupConf *BackupConfig) *BackupShowCmd {
+	conf := BackupShowConfig{
+		BackupConfig: backupConf,
+	}
+	cmd := BackupShowCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl backup show", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:        "show",
+		ShortUsage:  "grafctl backup show <src>",
+		ShortHelp:   "Show the content of a backup",
+		FlagSet:     fs,
+		Exec:        cmd.Exec,
+		Subcommands: []*ffcli.Command{},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the backupShow command
+func (c *BackupShowCmd) RegisterFlags(fs *flag.FlagSet) {
+}
+
+// Exec executes the backupShow command
+func (c *BackupShowCmd) Exec(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing backup location, eg; grafctl backup show s3://grafana-backup-bucket/monitoring-2020-12-20.json.gz")
+	}
+	printer := backupPrinter{w: os.Stdout}
+	manifest, err := c.Conf.readBackup(ctx, args[0], &printer)
+	if err != nil {
+		return err
+	}
+	printer.manifest(manifest)
+	return nil
+}
+
+// backupPrinter is a backupVisitor printing the content of every section of a backup
+type backupPrinter struct {
+	w io.Writer
+
+	dashboards *tablewriter.Table
+	count      int
+}
+
+func (p *backupPrinter) begin(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	if org != nil {
+		fmt.Fprintf(p.w, "Org %d: %s\n\n", org.ID, org.Name)
+	}
+
+	fmt.Fprintf(p.w, "Datasources: %d\n", len(grafanaBackup.Datasources))
+	table := tablewriter.NewWriter(p.w)
+	table.SetHeader([]string{"UID", "Name", "Type"})
+	for _, datasource := range grafanaBackup.Datasources {
+		table.Append([]string{datasource.UID, datasource.Name, datasource.Type})
+	}
+	table.Render()
+
+	fmt.Fprintf(p.w, "\nFolders: %d\n", len(grafanaBackup.Folders))
+	table = tablewriter.NewWriter(p.w)
+	table.SetHeader([]string{"UID", "Title", "Parent UID"})
+	for _, folder := range grafanaBackup.Folders {
+		table.Append([]string{folder.UID, folder.Title, folder.ParentUID})
+	}
+	table.Render()
+
+	if len(grafanaBackup.Users) > 0 {
+		fmt.Fprintf(p.w, "\nUsers: %d\n", len(grafanaBackup.Users))
+		table = tablewriter.NewWriter(p.w)
+		table.SetHeader([]string{"Login", "Email", "Role"})
+		for _, user := range grafanaBackup.Users {
+			table.Append([]string{user.Login, user.Email, user.Role})
+		}
+		table.Render()
+	}
+
+	if len(grafanaBackup.Teams) > 0 {
+		fmt.Fprintf(p.w, "\nTeams: %d\n", len(grafanaBackup.Teams))
+		table = tablewriter.NewWriter(p.w)
+		table.SetHeader([]string{"Name", "Members"})
+		for _, team := range grafanaBackup.Teams {
+			table.Append([]string{team.Name, strconv.Itoa(len(team.Members))})
+		}
+		table.Render()
+	}
+
+	if len(grafanaBackup.LibraryElements) > 0 {
+		fmt.Fprintf(p.w, "\nLibrary elements: %d\n", len(grafanaBackup.LibraryElements))
+		table = tablewriter.NewWriter(p.w)
+		table.SetHeader([]string{"UID", "Name", "Folder UID"})
+		for _, element := range grafanaBackup.LibraryElements {
+			table.Append([]string{element.UID, element.Name, element.FolderUID})
+		}
+		table.Render()
+	}
+
+	if alerting := grafanaBackup.Alerting; alerting != nil {
+		fmt.Fprintf(p.w, "\nAlert rule groups: %d\n", len(alerting.RuleGroups))
+		table = tablewriter.NewWriter(p.w)
+		table.SetHeader([]string{"Folder UID", "Title", "Rules"})
+		for _, ruleGroup := range alerting.RuleGroups {
+			table.Append([]string{ruleGroup.FolderUID, ruleGroup.Title, strconv.Itoa(len(ruleGroup.Rules))})
+		}
+		table.Render()
+		fmt.Fprintf(p.w, "Contact points: %d, mute timings: %d, templates: %d\n", len(alerting.ContactPoints), len(alerting.MuteTimings), len(alerting.Templates))
+	}
+
+	p.count = 0
+	p.dashboards = tablewriter.NewWriter(p.w)
+	p.dashboards.SetHeader([]string{"UID", "Title", "Folder", "Version"})
+	return nil
+}
+
+func (p *backupPrinter) dashboard(ctx context.Context, dashboard *grafsdk.DashboardWithMeta) error {
+	p.count++
+	p.dashboards.Append([]string{
+		dashboard.Dashboard.Get("uid").MustString(),
+		dashboard.Dashboard.Get("title").MustString(),
+		dashboard.Meta.Get("folderTitle").MustString(),
+		strconv.FormatInt(dashboard.Dashboard.Get("version").MustInt64(), 10),
+	})
+	return nil
+}
+
+// manifest prints the manifest of the backup, it is decoded after every section
+func (p *backupPrinter) manifest(manifest *BackupManifest) {
+	if manifest == nil {
+		fmt.Fprintln(p.w, "Manifest: none, the backup was taken before grafctl wrote manifests")
+		return
+	}
+	fmt.Fprintf(p.w, "Manifest: format version %d, grafctl %s\n", manifest.FormatVersion, manifest.GrafctlVersion)
+	fmt.Fprintf(p.w, "Source: %s\n", manifest.Source)
+	fmt.Fprintf(p.w, "Created: %s\n", manifest.Created.Format(time.RFC3339))
+	if manifest.Grafana != nil {
+		fmt.Fprintf(p.w, "Grafana: %s (%s)\n", manifest.Grafana.Version, manifest.Grafana.Commit)
+	}
+}
+
+func (p *backupPrinter) end(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	fmt.Fprintf(p.w, "\nDashboards: %d\n", p.count)
+	p.dashboards.Render()
+	if len(grafanaBackup.FolderPermissions) > 0 || len(grafanaBackup.DashboardPermissions) > 0 {
+		fmt.Fprintf(p.w, "Permissions: %d folder(s), %d dashboard(s)\n", len(grafanaBackup.FolderPermissions), len(grafanaBackup.DashboardPermissions))
+	}
+	fmt.Fprintln(p.w)
+	return nil
+}
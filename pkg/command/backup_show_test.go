@@ -0,0 +1,34 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupPrinter(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	mainOrg := grafana.orgs[1]
+	mainOrg.addDatasource("prometheus", "prometheus")
+	mainOrg.addDashboard("main-dash", "Main", mainOrg.addFolder("main-folder", "Main Folder"))
+	teamOrg := grafana.addOrg(2, "Team")
+	teamOrg.addDashboard("team-dash", "Team", nil)
+	src := backupFile(t, grafana, BackupOptions{AllOrgs: true})
+
+	var buf bytes.Buffer
+	conf := &RootConfig{}
+	_, err := conf.readBackup(context.Background(), src, &backupPrinter{w: &buf})
+	assert.NoError(t, err)
+	out := buf.String()
+
+	assert.Contains(t, out, "Org 1: Main Org.")
+	assert.Contains(t, out, "Org 2: Team")
+	assert.Contains(t, out, "Datasources: 1")
+	assert.Contains(t, out, "prometheus-uid")
+	assert.Contains(t, out, "main-folder")
+	assert.Contains(t, out, "Dashboards: 1")
+	assert.Regexp(t, `main-dash\s+\|\s+Main\s+\|\s+Main Folder\s+\|\s+1`, out)
+	assert.Regexp(t, `team-dash\s+\|\s+Team\s+\|\s+General\s+\|\s+1`, out)
+}
@@ -0,0 +1,280 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/peterbourgon/ff/v2/ffcli"
+)
+
+// BackupVerifyConfig has the config for the backupVerify command and a reference to the backup command config
+type BackupVerifyConfig struct {
+	*BackupConfig
+}
+
+// BackupVerifyCmd wraps the backupVerify config and a ffcli.Command
+type BackupVerifyCmd struct {
+	Conf *BackupVerifyConfig
+
+	*ffcli.Command
+}
+
+// NewBackupVerifyCmd creates a new BackupVerifyCmd
+func NewBackupVerifyCmd(backupConf *BackupConfig) *BackupVerifyCmd {
+	conf := BackupVerifyConfig{
+		BackupConfig: backupConf,
+	}
+	cmd := BackupVerifyCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl backup verify", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:        "verify",
+		ShortUsage:  "grafctl backup verify <src>",
+		ShortHelp:   "Verify the integrity of a backup",
+		FlagSet:     fs,
+		Exec:        cmd.Exec,
+		Subcommands: []*ffcli.Command{},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the backupVerify command
+func (c *BackupVerifyCmd) RegisterFlags(fs *flag.FlagSet) {
+}
+
+// Exec executes the backupVerify command
+func (c *BackupVerifyCmd) Exec(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing backup location, eg; grafctl backup verify s3://grafana-backup-bucket/monitoring-2020-12-20.json.gz")
+	}
+	verifier := backupVerifier{}
+	manifest, err := c.Conf.readBackup(ctx, args[0], &verifier)
+	if err != nil {
+		return fmt.Errorf("decode backup: %w", err)
+	}
+	return verifier.report(os.Stdout, manifest)
+}
+
+// verifiedSection has the checksums computed while decoding a backup section
+type verifiedSection struct {
+	org       *grafsdk.Org
+	checksums map[string]string
+	// folders maps the uid, id and title of the section folders to be able to resolve dashboard folders
+	folderUIDs   map[string]bool
+	folderIDs    map[int64]bool
+	folderTitles map[string]bool
+}
+
+// backupVerifier is a backupVisitor checking the integrity of a backup
+type backupVerifier struct {
+	sections []*verifiedSection
+	problems []string
+}
+
+func (v *backupVerifier) problemf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *backupVerifier) begin(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	section := verifiedSection{
+		org:          org,
+		checksums:    map[string]string{},
+		folderUIDs:   map[string]bool{},
+		folderIDs:    map[int64]bool{},
+		folderTitles: map[string]bool{},
+	}
+	v.sections = append(v.sections, &section)
+
+	for _, datasource := range grafanaBackup.Datasources {
+		if err := v.add(datasourceKey(datasource), datasource); err != nil {
+			return err
+		}
+	}
+	for _, folder := range grafanaBackup.Folders {
+		if err := v.add(folderKey(folder), folder); err != nil {
+			return err
+		}
+		section.folderUIDs[folder.UID] = true
+		section.folderIDs[folder.ID] = true
+		section.folderTitles[folder.Title] = true
+	}
+	for _, user := range grafanaBackup.Users {
+		if err := v.add(userKey(user), user); err != nil {
+			return err
+		}
+	}
+	for _, team := range grafanaBackup.Teams {
+		if err := v.add(teamKey(team), team); err != nil {
+			return err
+		}
+	}
+	for _, permissions := range grafanaBackup.FolderPermissions {
+		if err := v.add(folderPermissionsKey(permissions), permissions); err != nil {
+			return err
+		}
+	}
+	for _, element := range grafanaBackup.LibraryElements {
+		if err := v.add(libraryElementKey(element), element); err != nil {
+			return err
+		}
+		if element.FolderUID != "" && !section.folderUIDs[element.FolderUID] {
+			v.problemf("%s: %s references missing folder uid %s", sectionName(org), libraryElementKey(element), element.FolderUID)
+		}
+	}
+	if alerting := grafanaBackup.Alerting; alerting != nil {
+		for _, ruleGroup := range alerting.RuleGroups {
+			if err := v.add(ruleGroupKey(ruleGroup), ruleGroup); err != nil {
+				return err
+			}
+			if !section.folderUIDs[ruleGroup.FolderUID] {
+				v.problemf("%s: %s references missing folder uid %s", sectionName(org), ruleGroupKey(ruleGroup), ruleGroup.FolderUID)
+			}
+		}
+		for _, contactPoint := range alerting.ContactPoints {
+			if err := v.add(contactPointKey(contactPoint), contactPoint); err != nil {
+				return err
+			}
+		}
+		for _, muteTiming := range alerting.MuteTimings {
+			if err := v.add(muteTimingKey(muteTiming), muteTiming); err != nil {
+				return err
+			}
+		}
+		for _, template := range alerting.Templates {
+			if err := v.add(templateKey(template), template); err != nil {
+				return err
+			}
+		}
+		if alerting.Policies != nil {
+			if err := v.add(policiesKey, alerting.Policies); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// add records the checksum of an item of the current section
+func (v *backupVerifier) add(key string, item interface{}) error {
+	section := v.sections[len(v.sections)-1]
+	sum, err := itemChecksum(item)
+	if err != nil {
+		return fmt.Errorf("checksum %s: %w", key, err)
+	}
+	if _, ok := section.checksums[key]; ok {
+		v.problemf("%s: duplicate %s", sectionName(section.org), key)
+	}
+	section.checksums[key] = sum
+	return nil
+}
+
+func (v *backupVerifier) dashboard(ctx context.Context, dashboard *grafsdk.DashboardWithMeta) error {
+	key := dashboardKey(dashboard)
+	if err := v.add(key, dashboard); err != nil {
+		return err
+	}
+
+	// dashboards outside of the general folder must reference a folder of the archive
+	section := v.sections[len(v.sections)-1]
+	folderUID := dashboard.Meta.Get("folderUid").MustString()
+	folderID := dashboard.Meta.Get("folderId").MustInt64()
+	folderTitle := dashboard.Meta.Get("folderTitle").MustString()
+	switch {
+	case folderUID != "":
+		if !section.folderUIDs[folderUID] {
+			v.problemf("%s: %s references missing folder uid %s", sectionName(section.org), key, folderUID)
+		}
+	case folderID != 0:
+		if !section.folderIDs[folderID] {
+			v.problemf("%s: %s references missing folder id %d", sectionName(section.org), key, folderID)
+		}
+	case folderTitle != "" && folderTitle != "General":
+		if !section.folderTitles[folderTitle] {
+			v.problemf("%s: %s references missing folder %q", sectionName(section.org), key, folderTitle)
+		}
+	}
+	return nil
+}
+
+// end records the checksums of the members following the dashboards
+func (v *backupVerifier) end(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	for _, permissions := range grafanaBackup.DashboardPermissions {
+		if err := v.add(dashboardPermissionsKey(permissions), permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verify compares the computed checksums with the manifest
+func (v *backupVerifier) verify(manifest *BackupManifest) {
+	if manifest.FormatVersion > backupFormatVersion {
+		v.problemf("unsupported format version %d, the backup was written by a newer grafctl %s", manifest.FormatVersion, manifest.GrafctlVersion)
+		return
+	}
+	if len(manifest.Sections) != len(v.sections) {
+		v.problemf("the manifest has %d section(s), the archive has %d", len(manifest.Sections), len(v.sections))
+		return
+	}
+	for i, section := range v.sections {
+		expected := manifest.Sections[i].Checksums
+		keys := []string{}
+		for key := range expected {
+			keys = append(keys, key)
+		}
+		for key := range section.checksums {
+			if _, ok := expected[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sum, ok := section.checksums[key]
+			switch {
+			case !ok:
+				v.problemf("%s: %s is in the manifest but missing from the archive", sectionName(section.org), key)
+			case expected[key] == "":
+				v.problemf("%s: %s is missing from the manifest", sectionName(section.org), key)
+			case expected[key] != sum:
+				v.problemf("%s: %s checksum mismatch", sectionName(section.org), key)
+			}
+		}
+	}
+}
+
+// report verifies the manifest and prints the result, it fails when the backup has problems
+func (v *backupVerifier) report(w io.Writer, manifest *BackupManifest) error {
+	items := 0
+	for _, section := range v.sections {
+		items += len(section.checksums)
+	}
+	if manifest == nil {
+		fmt.Fprintln(w, "warning: the backup has no manifest, checksums were not verified")
+	} else {
+		v.verify(manifest)
+	}
+	for _, problem := range v.problems {
+		fmt.Fprintln(w, problem)
+	}
+	if len(v.problems) > 0 {
+		return fmt.Errorf("backup verification failed with %d problem(s)", len(v.problems))
+	}
+	fmt.Fprintf(w, "ok: %d section(s), %d item(s)\n", len(v.sections), items)
+	return nil
+}
+
+func sectionName(org *grafsdk.Org) string {
+	if org == nil {
+		return "backup"
+	}
+	return "org " + strconv.FormatInt(org.ID, 10) + " " + org.Name
+}
@@ -0,0 +1,98 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupVerifier(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	mainOrg := grafana.orgs[1]
+	mainOrg.addDatasource("prometheus", "prometheus")
+	mainOrg.addDashboard("main-dash", "Main", mainOrg.addFolder("main-folder", "Main Folder"))
+	mainOrg.addDashboard("general-dash", "General", nil)
+	src := backupFile(t, grafana, BackupOptions{})
+	conf := &RootConfig{}
+
+	verify := func(tamper func(manifest *BackupManifest)) (string, error) {
+		verifier := backupVerifier{}
+		manifest, err := conf.readBackup(context.Background(), src, &verifier)
+		assert.NoError(t, err)
+		if tamper != nil {
+			tamper(manifest)
+		}
+		var buf bytes.Buffer
+		err = verifier.report(&buf, manifest)
+		return buf.String(), err
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		out, err := verify(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "ok: 1 section(s), 4 item(s)\n", out)
+	})
+
+	t.Run("empty grafana", func(t *testing.T) {
+		src := backupFile(t, newFakeGrafana(t), BackupOptions{})
+		verifier := backupVerifier{}
+		manifest, err := conf.readBackup(context.Background(), src, &verifier)
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		assert.NoError(t, verifier.report(&buf, manifest))
+		assert.Equal(t, "ok: 1 section(s), 0 item(s)\n", buf.String())
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		out, err := verify(func(manifest *BackupManifest) {
+			checksums := manifest.Sections[0].Checksums
+			checksums["dashboard/main-dash"] = checksum([]byte("tampered"))
+			delete(checksums, "folder/main-folder")
+			checksums["dashboard/removed"] = checksum([]byte("removed"))
+		})
+		assert.EqualError(t, err, "backup verification failed with 3 problem(s)")
+		assert.Contains(t, out, "dashboard/main-dash checksum mismatch")
+		assert.Contains(t, out, "dashboard/removed is in the manifest but missing from the archive")
+		assert.Contains(t, out, "folder/main-folder is missing from the manifest")
+	})
+
+	t.Run("newer format", func(t *testing.T) {
+		_, err := verify(func(manifest *BackupManifest) {
+			manifest.FormatVersion = backupFormatVersion + 1
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		out, err := verify(func(manifest *BackupManifest) {
+			*manifest = BackupManifest{}
+		})
+		assert.Error(t, err)
+		assert.Contains(t, out, "the manifest has 0 section(s), the archive has 1")
+
+		verifier := backupVerifier{}
+		_, err = conf.readBackup(context.Background(), src, &verifier)
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		assert.NoError(t, verifier.report(&buf, nil))
+		assert.Contains(t, buf.String(), "checksums were not verified")
+	})
+}
+
+func TestBackupVerifierMissingFolder(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	mainOrg := grafana.orgs[1]
+	mainOrg.addDashboard("orphan-dash", "Orphan", &grafsdk.Folder{ID: 99, UID: "removed-folder", Title: "Removed"})
+	src := backupFile(t, grafana, BackupOptions{})
+
+	verifier := backupVerifier{}
+	manifest, err := (&RootConfig{}).readBackup(context.Background(), src, &verifier)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	err = verifier.report(&buf, manifest)
+	assert.EqualError(t, err, "backup verification failed with 1 problem(s)")
+	assert.Contains(t, buf.String(), "dashboard/orphan-dash references missing folder uid removed-folder")
+}
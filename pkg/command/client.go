@@ -1,9 +1,8 @@
 package command
 
 import (
-	"bytes"
+	"compress/gzip"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -11,13 +10,13 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/diogogmt/grafctl/pkg/grafsdk"
 	"github.com/diogogmt/grafctl/pkg/simplejson"
-	"github.com/diogogmt/grafctl/pkg/utils"
 )
 
 type BackupProvider string
@@ -25,6 +24,7 @@ type BackupProvider string
 var (
 	GCSBackupProvider   = BackupProvider("gcs")
 	LocalBackupProvider = BackupProvider("local")
+	S3BackupProvider    = BackupProvider("s3")
 )
 
 const dataSourceTypePrometheus = "prometheus"
@@ -35,6 +35,65 @@ type GrafanaBackup struct {
 	Datasources []*grafsdk.Datasource        `json:"datasources"`
 	Folders     []*grafsdk.Folder            `json:"folders"`
 	Dashboards  []*grafsdk.DashboardWithMeta `json:"dashboards"`
+	// FolderPermissions and DashboardPermissions have the permissions granted on the folders and the dashboards,
+	// the dashboard permissions follow the dashboards in the archive since dashboards must exist to restore them
+	FolderPermissions    []*ResourcePermissions `json:"folderPermissions,omitempty"`
+	DashboardPermissions []*ResourcePermissions `json:"dashboardPermissions,omitempty"`
+	// Users and Teams are only backed up with -include users,teams, the users are restored before the teams and the
+	// permissions referencing them
+	Users []*grafsdk.OrgUser `json:"users,omitempty"`
+	Teams []*TeamBackup      `json:"teams,omitempty"`
+	// LibraryElements are restored before the dashboards referencing them
+	LibraryElements []*grafsdk.LibraryElement `json:"libraryElements,omitempty"`
+	// Alerting is nil for backups of grafana servers without the alerting provisioning API
+	Alerting *AlertingBackup `json:"alerting,omitempty"`
+	// Orgs has a section per organization when backing up with -all-orgs
+	Orgs []*GrafanaOrgBackup `json:"orgs,omitempty"`
+}
+
+// fields maps the archive keys of a backup section to the fields they are decoded into.
+// Dashboards and orgs are streamed and therefore not part of the map.
+func (b *GrafanaBackup) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"datasources":          &b.Datasources,
+		"folders":              &b.Folders,
+		"users":                &b.Users,
+		"teams":                &b.Teams,
+		"folderPermissions":    &b.FolderPermissions,
+		"libraryElements":      &b.LibraryElements,
+		"alerting":             &b.Alerting,
+		"dashboardPermissions": &b.DashboardPermissions,
+	}
+}
+
+// empty reports whether the section has nothing to restore
+func (b *GrafanaBackup) empty() bool {
+	return len(b.Datasources) == 0 && len(b.Folders) == 0 && len(b.Dashboards) == 0 && len(b.LibraryElements) == 0 &&
+		len(b.Users) == 0 && len(b.Teams) == 0 &&
+		b.Alerting == nil && len(b.FolderPermissions) == 0 && len(b.DashboardPermissions) == 0
+}
+
+// GrafanaOrgBackup is the backup of a single organization
+type GrafanaOrgBackup struct {
+	Org *grafsdk.Org `json:"org"`
+	GrafanaBackup
+}
+
+// BackupOptions has the settings of a BackupGrafana run
+type BackupOptions struct {
+	// Store is where the backup archive is written to
+	Store BackupStore
+	// EncryptionKey encrypts the archive when set
+	EncryptionKey *EncryptionKey
+	// AllOrgs backs up every organization visible to the credentials as a separate section
+	AllOrgs bool
+	// IncludeUsers and IncludeTeams back up the users of the org and the teams with their members
+	IncludeUsers bool
+	IncludeTeams bool
+	// Concurrency is the number of dashboards fetched in parallel
+	Concurrency int
+	// Progress is called after every dashboard is fetched
+	Progress func(done int, total int)
 }
 
 type Client struct {
@@ -44,98 +103,436 @@ type Client struct {
 	verbose bool
 }
 
-func NewClient(apiURL string, apiKey string, verbose bool) *Client {
+func NewClient(apiURL string, apiKey string, verbose bool, opts ...grafsdk.Option) *Client {
 	return &Client{
-		Client:  grafsdk.New(apiURL, apiKey),
+		Client:  grafsdk.New(apiURL, apiKey, opts...),
 		apiURL:  apiURL,
 		apiKey:  apiKey,
 		verbose: verbose,
 	}
 }
 
-func (c *Client) BackupGrafana(ctx context.Context, provider BackupProvider, dest string) error {
-	var gcsBucket *storage.BucketHandle
-	switch provider {
-	case GCSBackupProvider:
-		if dest == "" {
-			return fmt.Errorf("missing bucket location")
+// withOrg returns a copy of the client sending every request to the given organization
+func (c *Client) withOrg(orgID int64) *Client {
+	orgClient := *c
+	orgClient.Client = c.Client.WithOrg(orgID)
+	return &orgClient
+}
+
+// resolveOrg finds an organization by numeric id or by name
+func (c *Client) resolveOrg(ctx context.Context, idOrName string) (*grafsdk.Org, error) {
+	if orgID, err := strconv.ParseInt(idOrName, 10, 64); err == nil {
+		return &grafsdk.Org{ID: orgID}, nil
+	}
+	org, err := c.GetOrgByName(ctx, idOrName)
+	if err == nil {
+		return org, nil
+	}
+	if !grafsdk.IsNotFound(err) && !grafsdk.IsUnauthorized(err) {
+		return nil, fmt.Errorf("GetOrgByName %s: %w", idOrName, err)
+	}
+	// looking up orgs by name requires server admin, fallback to the orgs of the signed in user
+	userOrgs, err := c.ListUserOrgs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListUserOrgs: %w", err)
+	}
+	for _, userOrg := range userOrgs {
+		if userOrg.Name == idOrName {
+			return userOrg, nil
+		}
+	}
+	return nil, fmt.Errorf("org %q not found", idOrName)
+}
+
+func (c *Client) BackupGrafana(ctx context.Context, opts BackupOptions) error {
+	if opts.Store == nil {
+		return fmt.Errorf("missing backup store")
+	}
+
+	u, err := url.Parse(c.apiURL)
+	if err != nil {
+		return err
+	}
+
+	backupName := formatBackupName(backupHost(u.Host), time.Now())
+	if opts.EncryptionKey != nil {
+		backupName += encryptedArchiveSuffix
+	}
+
+	// the archive is streamed to the store, cancelling the context before Close discards a failed backup
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	objectWriter, err := opts.Store.Put(ctx, backupName)
+	if err != nil {
+		return fmt.Errorf("Put %s: %w", backupName, err)
+	}
+	if err := c.writeBackup(ctx, opts, objectWriter); err != nil {
+		cancel()
+		objectWriter.Close()
+		return err
+	}
+	if err := objectWriter.Close(); err != nil {
+		return fmt.Errorf("Close %s: %w", backupName, err)
+	}
+	c.logd("backup written to %s", backupName)
+
+	return nil
+}
+
+// writeBackup writes the gzipped backup archive to w, dashboards are written as they are fetched
+func (c *Client) writeBackup(ctx context.Context, opts BackupOptions, w io.Writer) error {
+	var encryptWriter io.WriteCloser
+	if opts.EncryptionKey != nil {
+		var err error
+		if encryptWriter, err = newEncryptWriter(w, opts.EncryptionKey); err != nil {
+			return fmt.Errorf("newEncryptWriter: %w", err)
+		}
+		w = encryptWriter
+	}
+
+	gzipWriter, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return fmt.Errorf("gzip.NewWriterLevel: %w", err)
+	}
+	jsonWriter := newJSONStreamWriter(gzipWriter)
+
+	manifest := c.newManifest(ctx)
+	if err := jsonWriter.open('{'); err != nil {
+		return err
+	}
+	if opts.AllOrgs {
+		orgs, err := c.ListOrgs(ctx)
+		if err != nil {
+			return fmt.Errorf("ListOrgs: %w", err)
+		}
+		if err := jsonWriter.key("orgs"); err != nil {
+			return err
+		}
+		if err := jsonWriter.open('['); err != nil {
+			return err
+		}
+		for _, org := range orgs {
+			c.logd("backing up org %d:%q", org.ID, org.Name)
+			if err := jsonWriter.open('{'); err != nil {
+				return err
+			}
+			if err := jsonWriter.field("org", org); err != nil {
+				return err
+			}
+			section := newManifestSection(org)
+			if err := c.withOrg(org.ID).backupOrg(ctx, opts, jsonWriter, section); err != nil {
+				return fmt.Errorf("org %d %s: %w", org.ID, org.Name, err)
+			}
+			manifest.Sections = append(manifest.Sections, section)
+			if err := jsonWriter.close('}'); err != nil {
+				return err
+			}
+		}
+		if err := jsonWriter.close(']'); err != nil {
+			return err
 		}
-		gcsClient, err := storage.NewClient(ctx)
+	} else {
+		// like the build info, the org is informative
+		org, err := c.GetCurrentOrg(ctx)
 		if err != nil {
+			c.logd("GetCurrentOrg: %s", err)
+		}
+		section := newManifestSection(org)
+		if err := c.backupOrg(ctx, opts, jsonWriter, section); err != nil {
 			return err
 		}
-		gcsBucket = gcsClient.Bucket(dest)
-		if _, err := gcsBucket.Attrs(ctx); err != nil {
-			return fmt.Errorf("error getting bucket %q attributes: %s", dest, err)
+		manifest.Sections = append(manifest.Sections, section)
+	}
+	if err := jsonWriter.field("manifest", manifest); err != nil {
+		return err
+	}
+	if err := jsonWriter.close('}'); err != nil {
+		return err
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("gzipWriter.Close: %w", err)
+	}
+	if encryptWriter != nil {
+		if err := encryptWriter.Close(); err != nil {
+			return fmt.Errorf("encryptWriter.Close: %w", err)
 		}
+	}
+	return nil
+}
 
-	case LocalBackupProvider:
-		// nop
-	default:
-		return fmt.Errorf("provider %q not supported", provider)
+// newManifest returns the manifest of a backup of the client grafana without sections
+func (c *Client) newManifest(ctx context.Context) *BackupManifest {
+	manifest := BackupManifest{
+		FormatVersion:  backupFormatVersion,
+		GrafctlVersion: Version,
+		Created:        time.Now().UTC(),
+	}
+	if u, err := url.Parse(c.apiURL); err == nil {
+		u.User = nil
+		manifest.Source = u.String()
+	}
+	// the build info is informative, grafana servers behind some proxies do not expose the health endpoint
+	health, err := c.GetHealth(ctx)
+	if err != nil {
+		c.logd("GetHealth: %s", err)
+	} else {
+		manifest.Grafana = health
+	}
+	return &manifest
+}
+
+// fetchFolderTree returns every folder of the client org top-down, parents before their subfolders.
+// Grafana versions without nested folders ignore the parent uid and list the top-level folders again, the tree is then
+// not walked any further.
+func (c *Client) fetchFolderTree(ctx context.Context) ([]*grafsdk.Folder, error) {
+	folders, err := c.ListFolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, folder := range folders {
+		seen[folder.UID] = true
+	}
+	for n := 0; n < len(folders); n++ {
+		parent := folders[n]
+		subfolders, err := c.ListSubfolders(ctx, parent.UID)
+		if err != nil {
+			return nil, fmt.Errorf("ListSubfolders %s: %w", parent.UID, err)
+		}
+		if n == 0 && nestedFoldersUnsupported(folders, subfolders) {
+			c.logd("nested folders are not supported, listing the top-level folders only")
+			return folders, nil
+		}
+		for _, subfolder := range subfolders {
+			if seen[subfolder.UID] {
+				continue
+			}
+			seen[subfolder.UID] = true
+			subfolder.ParentUID = parent.UID
+			folders = append(folders, subfolder)
+		}
 	}
+	return folders, nil
+}
 
-	grafanaBackup := GrafanaBackup{}
-	var err error
+// nestedFoldersUnsupported reports whether the subfolders listed for the first top-level folder are top-level folders,
+// a folder is never its own subfolder with nested folders
+func nestedFoldersUnsupported(topLevel []*grafsdk.Folder, subfolders []*grafsdk.Folder) bool {
+	for _, subfolder := range subfolders {
+		for _, folder := range topLevel {
+			if subfolder.UID == folder.UID {
+				return true
+			}
+		}
+	}
+	return false
+}
 
+// backupOrg writes the datasources, folders, users, teams, library panels, alerting resources, dashboards and permissions of the client org as members of the open backup object
+// and records their checksums in the manifest section
+func (c *Client) backupOrg(ctx context.Context, opts BackupOptions, jsonWriter *jsonStreamWriter, section *ManifestSection) error {
 	// backup datasources
-	if grafanaBackup.Datasources, err = c.ListDatasources(ctx); err != nil {
+	datasources, err := c.ListDatasources(ctx)
+	if err != nil {
 		return err
 	}
-
-	// backup folders
-	if grafanaBackup.Folders, err = c.ListFolders(ctx); err != nil {
+	if err := writeItems(jsonWriter, section, "datasources", datasources, datasourceKey); err != nil {
 		return err
 	}
 
-	// backup dashboards
-	dashSearchResults, err := c.Search(ctx, grafsdk.DashTypeSearchOption())
+	// backup folders, parents are written before their subfolders
+	folders, err := c.fetchFolderTree(ctx)
 	if err != nil {
 		return err
 	}
-	for _, dashSearchResult := range dashSearchResults {
-		dashboard, err := c.GetDashboardByUID(ctx, dashSearchResult.UID)
+	if err := writeItems(jsonWriter, section, "folders", folders, folderKey); err != nil {
+		return err
+	}
+
+	// backup users and teams, they are opt-in since they need the org admin role
+	if opts.IncludeUsers {
+		users, err := c.ListOrgUsers(ctx)
+		if err != nil {
+			return fmt.Errorf("ListOrgUsers: %w", err)
+		}
+		if err := writeItems(jsonWriter, section, "users", users, userKey); err != nil {
+			return err
+		}
+	}
+	if opts.IncludeTeams {
+		teams, err := c.fetchTeams(ctx)
 		if err != nil {
 			return err
 		}
-		grafanaBackup.Dashboards = append(grafanaBackup.Dashboards, dashboard)
+		if err := writeItems(jsonWriter, section, "teams", teams, teamKey); err != nil {
+			return err
+		}
 	}
 
-	u, err := url.Parse(c.apiURL)
+	// backup folder permissions
+	folderPermissions, err := c.fetchFolderPermissions(ctx, folders, opts.Concurrency)
 	if err != nil {
 		return err
 	}
+	if len(folderPermissions) > 0 {
+		if err := writeItems(jsonWriter, section, "folderPermissions", folderPermissions, folderPermissionsKey); err != nil {
+			return err
+		}
+	}
 
-	now := time.Now().UTC()
-	backupName := fmt.Sprintf("%s-%s-%d.json.gz", strings.ReplaceAll(u.Host, ".", "_"), now.Format("2006-01-02"), now.UnixNano())
+	// backup library panels, grafana versions before library panels do not have the endpoint
+	libraryElements, err := c.ListLibraryElements(ctx)
+	switch {
+	case grafsdk.IsNotFound(err):
+		c.logd("skipping library elements: %s", err)
+	case err != nil:
+		return fmt.Errorf("ListLibraryElements: %w", err)
+	case len(libraryElements) > 0:
+		if err := writeItems(jsonWriter, section, "libraryElements", libraryElements, libraryElementKey); err != nil {
+			return err
+		}
+	}
 
-	backupBy, err := json.Marshal(grafanaBackup)
+	// backup alerting, before the dashboards so it is decoded with the folders on import
+	alerting, err := c.fetchAlerting(ctx)
 	if err != nil {
 		return err
 	}
+	if alerting != nil {
+		if err := writeAlerting(jsonWriter, section, alerting); err != nil {
+			return err
+		}
+	}
 
-	backupGzippedBy, err := utils.Gzip(ctx, backupBy)
+	// backup dashboards
+	dashSearchResults, err := c.SearchAll(ctx, grafsdk.DashTypeSearchOption())
 	if err != nil {
 		return err
 	}
-	switch provider {
-	case GCSBackupProvider:
-		ctx, cancel := context.WithTimeout(ctx, time.Second*15)
-		defer cancel()
-		objectWriter := gcsBucket.Object(backupName).NewWriter(ctx)
-		if _, err = io.Copy(objectWriter, bytes.NewReader(backupGzippedBy)); err != nil {
-			return err
+	uids := make([]string, 0, len(dashSearchResults))
+	for _, dashSearchResult := range dashSearchResults {
+		uids = append(uids, dashSearchResult.UID)
+	}
+	if err := jsonWriter.key("dashboards"); err != nil {
+		return err
+	}
+	if err := jsonWriter.open('['); err != nil {
+		return err
+	}
+	done := 0
+	// dashboards with an acl don't inherit the permissions of their folder, even when the acl is empty
+	hasACL := map[string]bool{}
+	if err := c.fetchDashboards(ctx, uids, opts.Concurrency, func(dashboard *grafsdk.DashboardWithMeta) error {
+		if dashboard.Meta.Get("hasAcl").MustBool() {
+			hasACL[dashboard.Dashboard.Get("uid").MustString()] = true
 		}
-		if err := objectWriter.Close(); err != nil {
+		sum, err := jsonWriter.checksumValue(dashboard)
+		if err != nil {
 			return err
 		}
-	case LocalBackupProvider:
-		p := filepath.Join(dest, backupName)
-		if err := os.WriteFile(p, backupGzippedBy, 0644); err != nil {
+		section.Checksums[dashboardKey(dashboard)] = sum
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, len(uids))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := jsonWriter.close(']'); err != nil {
+		return err
+	}
+
+	// backup dashboard permissions
+	dashboardPermissions, err := c.fetchDashboardPermissions(ctx, uids, hasACL, opts.Concurrency)
+	if err != nil {
+		return err
+	}
+	if len(dashboardPermissions) == 0 {
+		return nil
+	}
+	return writeItems(jsonWriter, section, "dashboardPermissions", dashboardPermissions, dashboardPermissionsKey)
+}
+
+// fetchDashboards fetches the dashboards with a pool of concurrency workers and calls emit in the order of uids.
+// Workers only run a bounded window ahead of emit, and the first error cancels every in-flight request.
+func (c *Client) fetchDashboards(ctx context.Context, uids []string, concurrency int, emit func(dashboard *grafsdk.DashboardWithMeta) error) error {
+	return fetchOrdered(ctx, uids, concurrency, func(ctx context.Context, uid string) (*grafsdk.DashboardWithMeta, error) {
+		dashboard, err := c.GetDashboardByUID(ctx, uid)
+		if err != nil {
+			return nil, fmt.Errorf("GetDashboardByUID %s: %w", uid, err)
+		}
+		return dashboard, nil
+	}, emit)
+}
+
+// fetchOrdered calls fetch for every uid with a pool of concurrency workers and calls emit in the order of uids
+func fetchOrdered[T any](ctx context.Context, uids []string, concurrency int, fetch func(ctx context.Context, uid string) (T, error), emit func(item T) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type fetchResult struct {
+		item T
+		err  error
+	}
+	results := make([]chan fetchResult, len(uids))
+	for i := range results {
+		results[i] = make(chan fetchResult, 1)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// window bounds how many fetched items can wait to be emitted
+	window := make(chan struct{}, 2*concurrency)
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range uids {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				item, err := fetch(ctx, uids[i])
+				results[i] <- fetchResult{item: item, err: err}
+			}
+		}()
+	}
+
+	for i := range uids {
+		var result fetchResult
+		select {
+		case result = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if result.err != nil {
+			return result.err
+		}
+		if err := emit(result.item); err != nil {
 			return err
 		}
-	default:
-		return fmt.Errorf("provider %q not supported", provider)
+		<-window
 	}
 
 	return nil
@@ -0,0 +1,240 @@
+package command
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/stretchr/testify/assert"
+)
+
+// readLocalBackup reads the single backup archive written to dir
+func readLocalBackup(t *testing.T, dir string) *GrafanaBackup {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json.gz"))
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	f, err := os.Open(matches[0])
+	assert.NoError(t, err)
+	defer f.Close()
+	gzipReader, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+
+	grafanaBackup := GrafanaBackup{}
+	assert.NoError(t, json.NewDecoder(gzipReader).Decode(&grafanaBackup))
+	return &grafanaBackup
+}
+
+func TestBackupGrafanaAllOrgs(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	mainOrg := grafana.orgs[1]
+	mainOrg.addDatasource("prometheus", "prometheus")
+	mainOrg.addDashboard("main-dash", "Main", mainOrg.addFolder("main-folder", "Main Folder"))
+	teamOrg := grafana.addOrg(2, "Team")
+	teamOrg.addDatasource("postgres", "postgres")
+	teamOrg.addDashboard("team-dash", "Team", nil)
+
+	ctx := context.Background()
+
+	// single org
+	dir := t.TempDir()
+	assert.NoError(t, grafana.client().withOrg(2).BackupGrafana(ctx, BackupOptions{Store: NewLocalBackupStore(dir)}))
+	grafanaBackup := readLocalBackup(t, dir)
+	assert.Empty(t, grafanaBackup.Orgs)
+	assert.Len(t, grafanaBackup.Datasources, 1)
+	assert.Equal(t, "postgres", grafanaBackup.Datasources[0].Name)
+	assert.Len(t, grafanaBackup.Dashboards, 1)
+
+	// every org as a separate section
+	dir = t.TempDir()
+	assert.NoError(t, grafana.client().BackupGrafana(ctx, BackupOptions{Store: NewLocalBackupStore(dir), AllOrgs: true}))
+	grafanaBackup = readLocalBackup(t, dir)
+	assert.Empty(t, grafanaBackup.Dashboards)
+	assert.Len(t, grafanaBackup.Orgs, 2)
+	assert.Equal(t, "Main Org.", grafanaBackup.Orgs[0].Org.Name)
+	assert.Equal(t, "prometheus", grafanaBackup.Orgs[0].Datasources[0].Name)
+	assert.Equal(t, "Main Folder", grafanaBackup.Orgs[0].Folders[0].Title)
+	assert.Equal(t, "main-dash", grafanaBackup.Orgs[0].Dashboards[0].Dashboard.Get("uid").MustString())
+	assert.Equal(t, "Team", grafanaBackup.Orgs[1].Org.Name)
+	assert.Equal(t, "team-dash", grafanaBackup.Orgs[1].Dashboards[0].Dashboard.Get("uid").MustString())
+}
+
+func TestResolveOrg(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	grafana.addOrg(2, "Team")
+	client := grafana.client()
+	ctx := context.Background()
+
+	org, err := client.resolveOrg(ctx, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), org.ID)
+
+	org, err = client.resolveOrg(ctx, "Team")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), org.ID)
+
+	_, err = client.resolveOrg(ctx, "Missing")
+	assert.Error(t, err)
+
+	// the org header is only sent by the org client
+	assert.Equal(t, int64(0), client.OrgID())
+	assert.Equal(t, int64(2), client.withOrg(2).OrgID())
+}
+
+func TestFetchDashboards(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	org := grafana.orgs[1]
+	uids := []string{}
+	for i := 0; i < 20; i++ {
+		uid := fmt.Sprintf("dash-%02d", i)
+		org.addDashboard(uid, uid, nil)
+		uids = append(uids, uid)
+	}
+
+	// inject latency and track the number of in-flight dashboard requests
+	var inFlight, maxInFlight int32
+	grafana.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.HasPrefix(r.URL.Path, "/api/dashboards/uid/") {
+			return false
+		}
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		// the latency is reversed so later dashboards complete first
+		uid := strings.TrimPrefix(r.URL.Path, "/api/dashboards/uid/")
+		i, _ := strconv.Atoi(strings.TrimPrefix(uid, "dash-"))
+		time.Sleep(time.Duration(20-i) * time.Millisecond)
+		return false
+	}
+
+	ctx := context.Background()
+	client := grafana.client()
+
+	fetched := []string{}
+	err := client.fetchDashboards(ctx, uids, 4, func(dashboard *grafsdk.DashboardWithMeta) error {
+		fetched = append(fetched, dashboard.Dashboard.Get("uid").MustString())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uids, fetched)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(4))
+	assert.Greater(t, atomic.LoadInt32(&maxInFlight), int32(1))
+
+	// progress is reported for every dashboard of the backup
+	progress := []int{}
+	assert.NoError(t, client.BackupGrafana(ctx, BackupOptions{
+		Store:       NewLocalBackupStore(t.TempDir()),
+		Concurrency: 4,
+		Progress: func(done int, total int) {
+			assert.Equal(t, 20, total)
+			progress = append(progress, done)
+		},
+	}))
+	assert.Len(t, progress, 20)
+	assert.Equal(t, 20, progress[19])
+}
+
+func TestFetchDashboardsCancelOnError(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	org := grafana.orgs[1]
+	uids := []string{}
+	for i := 0; i < 50; i++ {
+		uid := fmt.Sprintf("dash-%02d", i)
+		org.addDashboard(uid, uid, nil)
+		uids = append(uids, uid)
+	}
+
+	// the first dashboard fails, every other request blocks until cancelled
+	var requests int32
+	grafana.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.HasPrefix(r.URL.Path, "/api/dashboards/uid/") {
+			return false
+		}
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/api/dashboards/uid/dash-00" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "bad dashboard"})
+			return true
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		return false
+	}
+
+	start := time.Now()
+	err := grafana.client().fetchDashboards(context.Background(), uids, 4, func(dashboard *grafsdk.DashboardWithMeta) error {
+		t.Fatal("unexpected dashboard")
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "dash-00")
+	assert.True(t, time.Since(start) < 5*time.Second)
+	// only the bounded window of dashboards was requested
+	assert.LessOrEqual(t, atomic.LoadInt32(&requests), int32(8))
+}
+
+func TestBackupGrafanaFailureRemovesArchive(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	grafana.orgs[1].addDashboard("dash", "Dash", nil)
+	grafana.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.HasPrefix(r.URL.Path, "/api/dashboards/uid/") {
+			return false
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "bad dashboard"})
+		return true
+	}
+
+	dir := t.TempDir()
+	err := grafana.client().BackupGrafana(context.Background(), BackupOptions{Store: NewLocalBackupStore(dir)})
+	assert.Error(t, err)
+	matches, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestFetchFolderTree(t *testing.T) {
+	grafana := newFakeGrafana(t)
+	org := grafana.orgs[1]
+	team := org.addFolder("team", "Team")
+	org.addSubfolder("team-alerts", "Alerts", team)
+	org.addFolder("ops", "Ops")
+	org.addFolder("infra", "Infra")
+	var requests int64
+	grafana.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/api/folders" {
+			atomic.AddInt64(&requests, 1)
+		}
+		return false
+	}
+
+	folders, err := grafana.client().fetchFolderTree(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, folders, 4)
+	assert.Equal(t, "team", folders[3].ParentUID)
+	// the top-level list and the subfolders of every folder
+	assert.Equal(t, int64(5), requests)
+
+	// without nested folders the tree is only listed once more to detect it
+	org.flatFolders = true
+	requests = 0
+	folders, err = grafana.client().fetchFolderTree(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, folders, 3)
+	assert.Equal(t, int64(2), requests)
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"context"
+	"flag"
+
+	"github.com/peterbourgon/ff/v2/ffcli"
+)
+
+// ConfigConfig has the config for the config command and a reference to the root command config
+type ConfigConfig struct {
+	*RootConfig
+}
+
+// ConfigCmd wraps the config config and a ffcli.Command
+type ConfigCmd struct {
+	Conf *ConfigConfig
+
+	*ffcli.Command
+}
+
+// NewConfigCmd creates a new ConfigCmd
+func NewConfigCmd(rootConf *RootConfig) *ConfigCmd {
+	conf := ConfigConfig{
+		RootConfig: rootConf,
+	}
+	cmd := ConfigCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl config", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:       "config",
+		ShortUsage: "grafctl config",
+		ShortHelp:  "Manage grafctl contexts",
+		FlagSet:    fs,
+		Exec:       cmd.Exec,
+		Subcommands: []*ffcli.Command{
+			NewConfigGetContextsCmd(&conf).Command,
+			NewConfigUseContextCmd(&conf).Command,
+			NewConfigSetContextCmd(&conf).Command,
+			NewConfigViewCmd(&conf).Command,
+		},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the config command
+func (c *ConfigCmd) RegisterFlags(fs *flag.FlagSet) {
+}
+
+// Exec executes the config command
+func (c *ConfigCmd) Exec(ctx context.Context, args []string) error {
+	c.FlagSet.Usage()
+	return nil
+}
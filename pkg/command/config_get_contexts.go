@@ -0,0 +1,70 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/peterbourgon/ff/v2/ffcli"
+)
+
+// ConfigGetContextsConfig has the config for the configGetContexts command and a reference to the config command config
+type ConfigGetContextsConfig struct {
+	*ConfigConfig
+}
+
+// ConfigGetContextsCmd wraps the configGetContexts config and a ffcli.Command
+type ConfigGetContextsCmd struct {
+	Conf *ConfigGetContextsConfig
+
+	*ffcli.Command
+}
+
+// NewConfigGetContextsCmd creates a new ConfigGetContextsCmd
+func NewConfigGetContextsCmd(configConf *ConfigConfig) *ConfigGetContextsCmd {
+	conf := ConfigGetContextsConfig{
+		ConfigConfig: configConf,
+	}
+	cmd := ConfigGetContextsCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl config get-contexts", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:        "get-contexts",
+		ShortUsage:  "grafctl config get-contexts",
+		ShortHelp:   "List the contexts of the config file",
+		FlagSet:     fs,
+		Exec:        cmd.Exec,
+		Subcommands: []*ffcli.Command{},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the configGetContexts command
+func (c *ConfigGetContextsCmd) RegisterFlags(fs *flag.FlagSet) {
+}
+
+// Exec executes the config get-contexts command
+func (c *ConfigGetContextsCmd) Exec(ctx context.Context, args []string) error {
+	contextsFile, err := LoadContextsFile(c.Conf.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Current", "Name", "URL", "Auth", "Org", "Folder"})
+
+	for _, configContext := range contextsFile.Contexts {
+		current := ""
+		if configContext.Name == contextsFile.CurrentContext {
+			current = "*"
+		}
+		table.Append([]string{current, configContext.Name, configContext.URL, configContext.Auth, configContext.Org, configContext.Folder})
+	}
+	table.Render()
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/peterbourgon/ff/v2/ffcli"
+)
+
+// ConfigSetContextConfig has the config for the configSetContext command and a reference to the config command config
+type ConfigSetContextConfig struct {
+	*ConfigConfig
+
+	Settings ConfigContext
+	TLS      ConfigTLS
+	Current  bool
+}
+
+// ConfigSetContextCmd wraps the configSetContext config and a ffcli.Command
+type ConfigSetContextCmd struct {
+	Conf *ConfigSetContextConfig
+
+	*ffcli.Command
+}
+
+// NewConfigSetContextCmd creates a new ConfigSetContextCmd
+func NewConfigSetContextCmd(configConf *ConfigConfig) *ConfigSetContextCmd {
+	conf := ConfigSetContextConfig{
+		ConfigConfig: configConf,
+	}
+	cmd := ConfigSetContextCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl config set-context", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:        "set-context",
+		ShortUsage:  "grafctl config set-context [flags] <name>",
+		ShortHelp:   "Create or update a context of the config file",
+		FlagSet:     fs,
+		Exec:        cmd.Exec,
+		Subcommands: []*ffcli.Command{},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the configSetContext command
+func (c *ConfigSetContextCmd) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Conf.Settings.URL, "url", "", "grafana server API URL")
+	fs.StringVar(&c.Conf.Settings.Auth, "auth", "", "authentication mode, eg; bearer/basic/cookie/none")
+	fs.StringVar(&c.Conf.Settings.Key, "key", "", "grafana server API key or service account token")
+	fs.StringVar(&c.Conf.Settings.User, "user", "", "grafana user for basic auth")
+	fs.StringVar(&c.Conf.Settings.Password, "password", "", "grafana password for basic auth")
+	fs.StringVar(&c.Conf.Settings.PasswordFile, "password-file", "", "file with the grafana password for basic auth")
+	fs.StringVar(&c.Conf.Settings.Org, "org", "", "grafana organization id or name")
+	fs.StringVar(&c.Conf.Settings.Folder, "folder", "", "default folder title")
+	fs.StringVar(&c.Conf.TLS.CACert, "ca-cert", "", "PEM bundle of certificate authorities used to verify the grafana server")
+	fs.StringVar(&c.Conf.TLS.ClientCert, "client-cert", "", "PEM client certificate used for mutual TLS")
+	fs.StringVar(&c.Conf.TLS.ClientKey, "client-key", "", "PEM client key used for mutual TLS")
+	fs.StringVar(&c.Conf.TLS.ServerName, "tls-server-name", "", "server name used to verify the grafana server certificate")
+	fs.BoolVar(&c.Conf.TLS.InsecureSkipVerify, "insecure-skip-verify", false, "skip the verification of the grafana server certificate")
+	fs.BoolVar(&c.Conf.Current, "current", false, "make it the current context")
+}
+
+// Exec executes the config set-context command, only the flags given are changed on an existing context
+func (c *ConfigSetContextCmd) Exec(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		log.Printf("missing context name")
+		c.FlagSet.Usage()
+		return nil
+	}
+	name := args[0]
+
+	contextsFile, err := LoadContextsFile(c.Conf.ConfigFile)
+	if err != nil {
+		return err
+	}
+	configContext := contextsFile.Context(name)
+	if configContext == nil {
+		configContext = &ConfigContext{Name: name}
+		contextsFile.Contexts = append(contextsFile.Contexts, configContext)
+	}
+
+	settings := c.Conf.Settings
+	c.FlagSet.Visit(func(f *flag.Flag) {
+		if configContext.TLS == nil {
+			configContext.TLS = &ConfigTLS{}
+		}
+		switch f.Name {
+		case "url":
+			configContext.URL = settings.URL
+		case "auth":
+			configContext.Auth = settings.Auth
+		case "key":
+			configContext.Key = settings.Key
+		case "user":
+			configContext.User = settings.User
+		case "password":
+			configContext.Password = settings.Password
+		case "password-file":
+			configContext.PasswordFile = settings.PasswordFile
+		case "org":
+			configContext.Org = settings.Org
+		case "folder":
+			configContext.Folder = settings.Folder
+		case "ca-cert":
+			configContext.TLS.CACert = c.Conf.TLS.CACert
+		case "client-cert":
+			configContext.TLS.ClientCert = c.Conf.TLS.ClientCert
+		case "client-key":
+			configContext.TLS.ClientKey = c.Conf.TLS.ClientKey
+		case "tls-server-name":
+			configContext.TLS.ServerName = c.Conf.TLS.ServerName
+		case "insecure-skip-verify":
+			configContext.TLS.InsecureSkipVerify = c.Conf.TLS.InsecureSkipVerify
+		}
+	})
+	if configContext.TLS != nil && *configContext.TLS == (ConfigTLS{}) {
+		configContext.TLS = nil
+	}
+
+	if c.Conf.Current || contextsFile.CurrentContext == "" {
+		contextsFile.CurrentContext = name
+	}
+	if err := contextsFile.Save(c.Conf.ConfigFile); err != nil {
+		return err
+	}
+	fmt.Printf("context %q saved to %s\n", name, c.Conf.ConfigFile)
+
+	return nil
+}
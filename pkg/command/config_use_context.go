@@ -0,0 +1,73 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/peterbourgon/ff/v2/ffcli"
+)
+
+// ConfigUseContextConfig has the config for the configUseContext command and a reference to the config command config
+type ConfigUseContextConfig struct {
+	*ConfigConfig
+}
+
+// ConfigUseContextCmd wraps the configUseContext config and a ffcli.Command
+type ConfigUseContextCmd struct {
+	Conf *ConfigUseContextConfig
+
+	*ffcli.Command
+}
+
+// NewConfigUseContextCmd creates a new ConfigUseContextCmd
+func NewConfigUseContextCmd(configConf *ConfigConfig) *ConfigUseContextCmd {
+	conf := ConfigUseContextConfig{
+		ConfigConfig: configConf,
+	}
+	cmd := ConfigUseContextCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl config use-context", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:        "use-context",
+		ShortUsage:  "grafctl config use-context <name>",
+		ShortHelp:   "Set the current context of the config file",
+		FlagSet:     fs,
+		Exec:        cmd.Exec,
+		Subcommands: []*ffcli.Command{},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the configUseContext command
+func (c *ConfigUseContextCmd) RegisterFlags(fs *flag.FlagSet) {
+}
+
+// Exec executes the config use-context command
+func (c *ConfigUseContextCmd) Exec(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		log.Printf("missing context name")
+		c.FlagSet.Usage()
+		return nil
+	}
+	name := args[0]
+
+	contextsFile, err := LoadContextsFile(c.Conf.ConfigFile)
+	if err != nil {
+		return err
+	}
+	if contextsFile.Context(name) == nil {
+		return fmt.Errorf("context %q not found in %s", name, c.Conf.ConfigFile)
+	}
+	contextsFile.CurrentContext = name
+	if err := contextsFile.Save(c.Conf.ConfigFile); err != nil {
+		return err
+	}
+	fmt.Printf("switched to context %q\n", name)
+
+	return nil
+}
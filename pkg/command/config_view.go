@@ -0,0 +1,80 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/peterbourgon/ff/v2/ffcli"
+	"gopkg.in/yaml.v2"
+)
+
+const redacted = "REDACTED"
+
+// ConfigViewConfig has the config for the configView command and a reference to the config command config
+type ConfigViewConfig struct {
+	*ConfigConfig
+
+	Raw bool
+}
+
+// ConfigViewCmd wraps the configView config and a ffcli.Command
+type ConfigViewCmd struct {
+	Conf *ConfigViewConfig
+
+	*ffcli.Command
+}
+
+// NewConfigViewCmd creates a new ConfigViewCmd
+func NewConfigViewCmd(configConf *ConfigConfig) *ConfigViewCmd {
+	conf := ConfigViewConfig{
+		ConfigConfig: configConf,
+	}
+	cmd := ConfigViewCmd{
+		Conf: &conf,
+	}
+	fs := flag.NewFlagSet("grafctl config view", flag.ExitOnError)
+	cmd.RegisterFlags(fs)
+
+	cmd.Command = &ffcli.Command{
+		Name:        "view",
+		ShortUsage:  "grafctl config view",
+		ShortHelp:   "Print the config file",
+		FlagSet:     fs,
+		Exec:        cmd.Exec,
+		Subcommands: []*ffcli.Command{},
+	}
+	return &cmd
+}
+
+// RegisterFlags registers a set of flags for the configView command
+func (c *ConfigViewCmd) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Conf.Raw, "raw", false, "print keys and passwords instead of redacting them")
+}
+
+// Exec executes the config view command
+func (c *ConfigViewCmd) Exec(ctx context.Context, args []string) error {
+	contextsFile, err := LoadContextsFile(c.Conf.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	if !c.Conf.Raw {
+		for _, configContext := range contextsFile.Contexts {
+			if configContext.Key != "" {
+				configContext.Key = redacted
+			}
+			if configContext.Password != "" {
+				configContext.Password = redacted
+			}
+		}
+	}
+
+	by, err := yaml.Marshal(contextsFile)
+	if err != nil {
+		return fmt.Errorf("yaml.Marshal: %w", err)
+	}
+	fmt.Print(string(by))
+
+	return nil
+}
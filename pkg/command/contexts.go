@@ -0,0 +1,177 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const envVarPrefix = "GRAFCTL"
+
+// ContextsFile is the grafctl config file holding the named contexts, eg; ~/.config/grafctl/config.yaml
+type ContextsFile struct {
+	CurrentContext string           `yaml:"current-context"`
+	Contexts       []*ConfigContext `yaml:"contexts"`
+}
+
+// ConfigContext has the settings used to reach a single grafana instance
+type ConfigContext struct {
+	Name         string     `yaml:"name"`
+	URL          string     `yaml:"url,omitempty"`
+	Auth         string     `yaml:"auth,omitempty"`
+	Key          string     `yaml:"key,omitempty"`
+	User         string     `yaml:"user,omitempty"`
+	Password     string     `yaml:"password,omitempty"`
+	PasswordFile string     `yaml:"password-file,omitempty"`
+	Org          string     `yaml:"org,omitempty"`
+	Folder       string     `yaml:"folder,omitempty"`
+	TLS          *ConfigTLS `yaml:"tls,omitempty"`
+}
+
+// ConfigTLS has the TLS settings of a context
+type ConfigTLS struct {
+	CACert             string `yaml:"ca-cert,omitempty"`
+	ClientCert         string `yaml:"client-cert,omitempty"`
+	ClientKey          string `yaml:"client-key,omitempty"`
+	ServerName         string `yaml:"server-name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify,omitempty"`
+}
+
+// DefaultConfigFile returns the default location of the grafctl config file
+func DefaultConfigFile() string {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configDir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configDir, "grafctl", "config.yaml")
+}
+
+// LoadContextsFile reads the config file, a missing file yields an empty config
+func LoadContextsFile(path string) (*ContextsFile, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &ContextsFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+	return decodeContextsFile(f)
+}
+
+func decodeContextsFile(r io.Reader) (*ContextsFile, error) {
+	contextsFile := ContextsFile{}
+	if err := yaml.NewDecoder(r).Decode(&contextsFile); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("yaml.Decode: %w", err)
+	}
+	return &contextsFile, nil
+}
+
+// Save writes the config file, creating its directory if needed
+func (f *ContextsFile) Save(path string) error {
+	if path == "" {
+		return fmt.Errorf("missing config file location")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	by, err := yaml.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("yaml.Marshal: %w", err)
+	}
+	return os.WriteFile(path, by, 0600)
+}
+
+// Context returns the context with the given name or nil
+func (f *ContextsFile) Context(name string) *ConfigContext {
+	for _, configContext := range f.Contexts {
+		if configContext.Name == name {
+			return configContext
+		}
+	}
+	return nil
+}
+
+// flags maps the context settings to the root flags they provide a value for
+func (c *ConfigContext) flags() map[string]string {
+	flags := map[string]string{
+		"url":           c.URL,
+		"auth":          c.Auth,
+		"key":           c.Key,
+		"user":          c.User,
+		"password":      c.Password,
+		"password-file": c.PasswordFile,
+		"org":           c.Org,
+		"folder":        c.Folder,
+	}
+	if c.TLS != nil {
+		flags["ca-cert"] = c.TLS.CACert
+		flags["client-cert"] = c.TLS.ClientCert
+		flags["client-key"] = c.TLS.ClientKey
+		flags["tls-server-name"] = c.TLS.ServerName
+		if c.TLS.InsecureSkipVerify {
+			flags["insecure-skip-verify"] = strconv.FormatBool(c.TLS.InsecureSkipVerify)
+		}
+	}
+	for name, value := range flags {
+		if value == "" {
+			delete(flags, name)
+		}
+	}
+	return flags
+}
+
+// parseContextsFile is a ff.ConfigFileParser setting the root flags from the selected context.
+// The context is picked with -context, GRAFCTL_CONTEXT or the current-context of the file.
+// Flags given on the command line or through GRAFCTL_* env vars take precedence over the context.
+func (c *RootConfig) parseContextsFile(r io.Reader, set func(name, value string) error) error {
+	contextsFile, err := decodeContextsFile(r)
+	if err != nil {
+		return err
+	}
+
+	name := c.Context
+	if name == "" {
+		name = os.Getenv(envVarName("context"))
+	}
+	explicit := name != ""
+	if name == "" {
+		name = contextsFile.CurrentContext
+	}
+	if name == "" {
+		return nil
+	}
+
+	configContext := contextsFile.Context(name)
+	if configContext == nil {
+		if explicit {
+			return fmt.Errorf("context %q not found in %s", name, c.ConfigFile)
+		}
+		return nil
+	}
+	c.Context = name
+
+	for flagName, value := range configContext.flags() {
+		if os.Getenv(envVarName(flagName)) != "" {
+			continue
+		}
+		if err := set(flagName, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// envVarName returns the env var ff reads for a root flag
+func envVarName(flagName string) string {
+	return envVarPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootCmdContexts(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "grafctl", "config.yaml")
+	contextsFile := &ContextsFile{
+		CurrentContext: "dev",
+		Contexts: []*ConfigContext{
+			{Name: "dev", URL: "http://localhost:3000", Key: "dev-key"},
+			{Name: "prod", URL: "https://grafana.example.com", Auth: "basic", User: "admin", PasswordFile: "/run/secrets/grafana", Folder: "Prod", TLS: &ConfigTLS{CACert: "/etc/ssl/ca.pem"}},
+		},
+	}
+	assert.NoError(t, contextsFile.Save(configFile))
+
+	// current context
+	rootCmd := NewRootCmd()
+	assert.NoError(t, rootCmd.Parse([]string{"-config", configFile, "dash", "ls"}))
+	assert.Equal(t, "dev", rootCmd.Conf.Context)
+	assert.Equal(t, "http://localhost:3000", rootCmd.Conf.APIURL)
+	assert.Equal(t, "dev-key", rootCmd.Conf.APIKey)
+	assert.Equal(t, "bearer", rootCmd.Conf.Auth)
+
+	// explicit context with a command line override
+	rootCmd = NewRootCmd()
+	assert.NoError(t, rootCmd.Parse([]string{"-config", configFile, "-context", "prod", "-user", "viewer", "dash", "ls"}))
+	assert.Equal(t, "https://grafana.example.com", rootCmd.Conf.APIURL)
+	assert.Equal(t, "basic", rootCmd.Conf.Auth)
+	assert.Equal(t, "viewer", rootCmd.Conf.User)
+	assert.Equal(t, "/run/secrets/grafana", rootCmd.Conf.PasswordFile)
+	assert.Equal(t, "Prod", rootCmd.Conf.Folder)
+	assert.Equal(t, "/etc/ssl/ca.pem", rootCmd.Conf.CACert)
+
+	// env vars override the context
+	t.Setenv("GRAFCTL_CONTEXT", "prod")
+	t.Setenv("GRAFCTL_URL", "https://grafana-replica.example.com")
+	rootCmd = NewRootCmd()
+	assert.NoError(t, rootCmd.Parse([]string{"-config", configFile, "dash", "ls"}))
+	assert.Equal(t, "prod", rootCmd.Conf.Context)
+	assert.Equal(t, "https://grafana-replica.example.com", rootCmd.Conf.APIURL)
+	assert.Equal(t, "admin", rootCmd.Conf.User)
+
+	// unknown context
+	rootCmd = NewRootCmd()
+	assert.Error(t, rootCmd.Parse([]string{"-config", configFile, "-context", "staging", "dash", "ls"}))
+
+	// missing config file
+	rootCmd = NewRootCmd()
+	assert.NoError(t, rootCmd.Parse([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml"), "-url", "http://localhost:3000", "dash", "ls"}))
+	assert.Equal(t, "http://localhost:3000", rootCmd.Conf.APIURL)
+}
+
+func TestConfigSetContext(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+
+	rootCmd := NewRootCmd()
+	assert.NoError(t, rootCmd.Parse([]string{"-config", configFile, "config", "set-context", "-url", "http://localhost:3000", "-key", "dev-key", "dev"}))
+	assert.NoError(t, rootCmd.Run(context.Background()))
+
+	rootCmd = NewRootCmd()
+	assert.NoError(t, rootCmd.Parse([]string{"-config", configFile, "config", "set-context", "-insecure-skip-verify", "-current", "staging"}))
+	assert.NoError(t, rootCmd.Run(context.Background()))
+
+	// only the given flags are updated
+	rootCmd = NewRootCmd()
+	assert.NoError(t, rootCmd.Parse([]string{"-config", configFile, "config", "set-context", "-key", "new-dev-key", "dev"}))
+	assert.NoError(t, rootCmd.Run(context.Background()))
+
+	contextsFile, err := LoadContextsFile(configFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", contextsFile.CurrentContext)
+	assert.Len(t, contextsFile.Contexts, 2)
+	assert.Equal(t, &ConfigContext{Name: "dev", URL: "http://localhost:3000", Key: "new-dev-key"}, contextsFile.Context("dev"))
+	assert.Equal(t, &ConfigContext{Name: "staging", TLS: &ConfigTLS{InsecureSkipVerify: true}}, contextsFile.Context("staging"))
+}
@@ -65,7 +65,11 @@ func (c *DashboardExportQueriesCmd) Exec(ctx context.Context, args []string) err
 		c.Conf.QueriesDir = "./queries"
 	}
 
-	if err := c.Conf.Client().ExportDashboardQueries(ctx, c.Conf.UID, c.Conf.QueriesDir, c.Conf.Overwrite); err != nil {
+	client, err := c.Conf.Client(ctx)
+	if err != nil {
+		return err
+	}
+	if err := client.ExportDashboardQueries(ctx, c.Conf.UID, c.Conf.QueriesDir, c.Conf.Overwrite); err != nil {
 		return err
 	}
 
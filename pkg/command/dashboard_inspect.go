@@ -51,7 +51,11 @@ func (c *DashboardInspectCmd) RegisterFlags(fs *flag.FlagSet) {
 
 // Exec executes the dashboard ls command
 func (c *DashboardInspectCmd) Exec(ctx context.Context, args []string) error {
-	dashboard, err := c.Conf.Client().GetDashboardByUID(ctx, c.Conf.UID)
+	client, err := c.Conf.Client(ctx)
+	if err != nil {
+		return err
+	}
+	dashboard, err := client.GetDashboardByUID(ctx, c.Conf.UID)
 	if err != nil {
 		return err
 	}
@@ -15,7 +15,10 @@ import (
 type DashboardLsConfig struct {
 	*DashboardConfig
 
-	UID string
+	UID     string
+	Query   string
+	Tags    stringsFlag
+	Starred bool
 }
 
 // DashboardLsCmd wraps the dashboardLs config and a ffcli.Command
@@ -50,11 +53,31 @@ func NewDashboardLsCmd(dashConf *DashboardConfig) *DashboardLsCmd {
 // RegisterFlags registers a set of flags for the dashboardLs command
 func (c *DashboardLsCmd) RegisterFlags(fs *flag.FlagSet) {
 	fs.StringVar(&c.Conf.UID, "uid", "", "dashboard UID")
+	fs.StringVar(&c.Conf.Query, "query", "", "only list dashboards with a title matching the query")
+	fs.Var(&c.Conf.Tags, "tag", "only list dashboards with the tag (repeatable)")
+	fs.BoolVar(&c.Conf.Starred, "starred", false, "only list starred dashboards")
 }
 
 // Exec executes the dashboard ls command
 func (c *DashboardLsCmd) Exec(ctx context.Context, args []string) error {
-	dashboards, err := c.Conf.Client().Search(ctx, grafsdk.DashTypeSearchOption())
+	client, err := c.Conf.Client(ctx)
+	if err != nil {
+		return err
+	}
+	searchOptions := []grafsdk.SearchOption{grafsdk.DashTypeSearchOption()}
+	if c.Conf.UID != "" {
+		searchOptions = append(searchOptions, grafsdk.DashboardUIDsSearchOption([]string{c.Conf.UID}))
+	}
+	if c.Conf.Query != "" {
+		searchOptions = append(searchOptions, grafsdk.QuerySearchOption(c.Conf.Query))
+	}
+	if len(c.Conf.Tags) > 0 {
+		searchOptions = append(searchOptions, grafsdk.TagSearchOption(c.Conf.Tags...))
+	}
+	if c.Conf.Starred {
+		searchOptions = append(searchOptions, grafsdk.StarredSearchOption())
+	}
+	dashboards, err := client.SearchAll(ctx, searchOptions...)
 	if err != nil {
 		return err
 	}
@@ -63,6 +86,9 @@ func (c *DashboardLsCmd) Exec(ctx context.Context, args []string) error {
 	table.SetHeader([]string{"UID", "Folder", "Title", "URL"})
 
 	for _, dashboard := range dashboards {
+		if c.Conf.Folder != "" && dashboard.FolderTitle != c.Conf.Folder {
+			continue
+		}
 		table.Append([]string{dashboard.UID, dashboard.FolderTitle, dashboard.Title, fmt.Sprintf("%s/%s", c.Conf.APIURL, dashboard.URL)})
 	}
 	table.Render()
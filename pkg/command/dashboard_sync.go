@@ -64,7 +64,11 @@ func (c *DashboardSyncCmd) Exec(ctx context.Context, args []string) error {
 		return nil
 	}
 
-	if err := c.Conf.Client().SyncDashboard(ctx, c.Conf.UID, c.Conf.QueriesDir); err != nil {
+	client, err := c.Conf.Client(ctx)
+	if err != nil {
+		return err
+	}
+	if err := client.SyncDashboard(ctx, c.Conf.UID, c.Conf.QueriesDir); err != nil {
 		return err
 	}
 
@@ -61,7 +61,11 @@ func (c *DashboardUpdatePanelsDescriptionsCmd) Exec(ctx context.Context, args []
 		return nil
 	}
 
-	if err := c.Conf.Client().UpdateDashboardPanelsDescription(ctx, c.Conf.UID, c.Conf.Overwrite, c.Conf.DryRun); err != nil {
+	client, err := c.Conf.Client(ctx)
+	if err != nil {
+		return err
+	}
+	if err := client.UpdateDashboardPanelsDescription(ctx, c.Conf.UID, c.Conf.Overwrite, c.Conf.DryRun); err != nil {
 		return err
 	}
 
@@ -0,0 +1,344 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// encryptedArchiveMagic starts every encrypted archive, it is followed by the JSON encoded archive header on a single line
+var encryptedArchiveMagic = []byte("grafctl-encrypted-backup\n")
+
+// gzipMagic starts every unencrypted archive
+var gzipMagic = []byte{0x1f, 0x8b}
+
+const (
+	encryptionKDFPBKDF2 = "pbkdf2-sha256"
+	encryptionKDFNone   = "none"
+
+	pbkdf2Iterations      = 600000
+	encryptionChunkSize   = 64 * 1024
+	encryptionKeySize     = 32
+	encryptionNoncePrefix = 7
+)
+
+// EncryptionKey encrypts the backup archives, either a passphrase stretched with PBKDF2 or a 256 bit key
+type EncryptionKey struct {
+	Passphrase string
+	Key        []byte
+}
+
+// LoadEncryptionKey reads the passphrase file or the key file, the key file holds a base64 encoded 256 bit key,
+// eg; the output of `openssl rand -base64 32`. Trailing new lines are ignored.
+func LoadEncryptionKey(passphraseFile string, keyFile string) (*EncryptionKey, error) {
+	switch {
+	case passphraseFile != "" && keyFile != "":
+		return nil, fmt.Errorf("set either a passphrase file or a key file")
+	case passphraseFile != "":
+		by, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("read passphrase file: %w", err)
+		}
+		passphrase := string(bytes.TrimRight(by, "\r\n"))
+		if passphrase == "" {
+			return nil, fmt.Errorf("empty passphrase file %s", passphraseFile)
+		}
+		return &EncryptionKey{Passphrase: passphrase}, nil
+	case keyFile != "":
+		by, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read key file: %w", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(by)))
+		if err != nil {
+			return nil, fmt.Errorf("decode key file: %w", err)
+		}
+		if len(key) != encryptionKeySize {
+			return nil, fmt.Errorf("key file %s must hold a %d bit key, got %d bits", keyFile, encryptionKeySize*8, len(key)*8)
+		}
+		return &EncryptionKey{Key: key}, nil
+	}
+	return nil, nil
+}
+
+// encryptionHeader describes how the archive key is wrapped and the archive chunks are sealed
+type encryptionHeader struct {
+	Version    int    `json:"version"`
+	Cipher     string `json:"cipher"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	// WrappedKey is the random archive key sealed with the key encryption key
+	WrappedKey  []byte `json:"wrappedKey"`
+	KeyNonce    []byte `json:"keyNonce"`
+	NoncePrefix []byte `json:"noncePrefix"`
+	ChunkSize   int    `json:"chunkSize"`
+}
+
+// kek returns the key encryption key of the header
+func (k *EncryptionKey) kek(header *encryptionHeader) ([]byte, error) {
+	switch header.KDF {
+	case encryptionKDFPBKDF2:
+		if k.Passphrase == "" {
+			return nil, fmt.Errorf("the archive is encrypted with a passphrase")
+		}
+		return pbkdf2.Key(sha256.New, k.Passphrase, header.Salt, header.Iterations, encryptionKeySize)
+	case encryptionKDFNone:
+		if k.Key == nil {
+			return nil, fmt.Errorf("the archive is encrypted with a key file")
+		}
+		return k.Key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %q", header.KDF)
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptWriter seals the archive in chunks, every chunk is written as a flag byte marking the last chunk,
+// the big endian length of the sealed chunk and the sealed chunk.
+// The nonce of a chunk is the nonce prefix, the chunk counter and the last chunk flag so chunks can't be
+// reordered, dropped or truncated without failing the decryption.
+type encryptWriter struct {
+	w           io.Writer
+	aead        cipher.AEAD
+	noncePrefix []byte
+	counter     uint32
+	buf         []byte
+	chunkSize   int
+}
+
+// newEncryptWriter writes the archive header to w and returns a writer encrypting the archive with a new random key
+func newEncryptWriter(w io.Writer, key *EncryptionKey) (io.WriteCloser, error) {
+	header := encryptionHeader{
+		Version:     1,
+		Cipher:      "aes-256-gcm",
+		NoncePrefix: make([]byte, encryptionNoncePrefix),
+		ChunkSize:   encryptionChunkSize,
+	}
+	if key.Passphrase != "" {
+		header.KDF = encryptionKDFPBKDF2
+		header.Iterations = pbkdf2Iterations
+		header.Salt = make([]byte, 16)
+		if _, err := rand.Read(header.Salt); err != nil {
+			return nil, err
+		}
+	} else {
+		header.KDF = encryptionKDFNone
+	}
+	if _, err := rand.Read(header.NoncePrefix); err != nil {
+		return nil, err
+	}
+
+	kek, err := key.kek(&header)
+	if err != nil {
+		return nil, err
+	}
+	kekGCM, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	archiveKey := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(archiveKey); err != nil {
+		return nil, err
+	}
+	header.KeyNonce = make([]byte, kekGCM.NonceSize())
+	if _, err := rand.Read(header.KeyNonce); err != nil {
+		return nil, err
+	}
+	header.WrappedKey = kekGCM.Seal(nil, header.KeyNonce, archiveKey, nil)
+
+	aead, err := newGCM(archiveKey)
+	if err != nil {
+		return nil, err
+	}
+	headerBy, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(append(encryptedArchiveMagic, headerBy...), '\n')); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, noncePrefix: header.NoncePrefix, chunkSize: header.ChunkSize}, nil
+}
+
+// chunkNonce returns the nonce of the chunk with the given counter
+func chunkNonce(noncePrefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, len(noncePrefix)+5)
+	copy(nonce, noncePrefix)
+	binary.BigEndian.PutUint32(nonce[len(noncePrefix):], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+func (e *encryptWriter) seal(chunk []byte, last bool) error {
+	if e.counter == ^uint32(0) {
+		return fmt.Errorf("archive too large")
+	}
+	sealed := e.aead.Seal(nil, chunkNonce(e.noncePrefix, e.counter, last), chunk, nil)
+	e.counter++
+	prefix := make([]byte, 5)
+	if last {
+		prefix[0] = 1
+	}
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(sealed)))
+	if _, err := e.w.Write(prefix); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	// a full chunk is only sealed once more data follows, the last chunk is sealed by Close
+	for len(e.buf) > e.chunkSize {
+		if err := e.seal(e.buf[:e.chunkSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[e.chunkSize:]
+	}
+	return len(p), nil
+}
+
+// Close seals the last chunk, it does not close the underlying writer
+func (e *encryptWriter) Close() error {
+	err := e.seal(e.buf, true)
+	e.buf = nil
+	return err
+}
+
+// decryptReader opens the chunks written by encryptWriter
+type decryptReader struct {
+	r           *bufio.Reader
+	aead        cipher.AEAD
+	noncePrefix []byte
+	counter     uint32
+	maxSealed   int
+	buf         []byte
+	done        bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(d.r, prefix); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("truncated encrypted archive")
+		}
+		return err
+	}
+	last := prefix[0] == 1
+	size := int(binary.BigEndian.Uint32(prefix[1:]))
+	if size > d.maxSealed {
+		return fmt.Errorf("invalid encrypted chunk size %d", size)
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("truncated encrypted archive")
+		}
+		return err
+	}
+	chunk, err := d.aead.Open(nil, chunkNonce(d.noncePrefix, d.counter, last), sealed, nil)
+	if err != nil {
+		return fmt.Errorf("decrypt chunk %d: %w", d.counter, err)
+	}
+	d.counter++
+	d.buf = chunk
+	if last {
+		d.done = true
+		if _, err := d.r.ReadByte(); err != io.EOF {
+			return fmt.Errorf("unexpected data after the encrypted archive")
+		}
+	}
+	return nil
+}
+
+// openArchive returns the gzipped archive read from r, encrypted archives are detected by their header and
+// decrypted with the key. Archives written without encryption are returned as is.
+func openArchive(r io.Reader, key *EncryptionKey) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(encryptedArchiveMagic))
+	if err != nil && !bytes.HasPrefix(magic, gzipMagic) {
+		return nil, fmt.Errorf("read archive header: %w", err)
+	}
+	if bytes.HasPrefix(magic, gzipMagic) {
+		return br, nil
+	}
+	if !bytes.Equal(magic, encryptedArchiveMagic) {
+		return nil, fmt.Errorf("unknown archive format")
+	}
+	if key == nil {
+		return nil, fmt.Errorf("the archive is encrypted, set -encryption-passphrase-file or -encryption-key-file")
+	}
+	br.Discard(len(encryptedArchiveMagic))
+
+	headerBy, err := br.ReadSlice('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read encryption header: %w", err)
+	}
+	header := encryptionHeader{}
+	if err := json.Unmarshal(headerBy, &header); err != nil {
+		return nil, fmt.Errorf("decode encryption header: %w", err)
+	}
+	if header.Version != 1 || header.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported encryption version %d cipher %q", header.Version, header.Cipher)
+	}
+	if len(header.NoncePrefix) != encryptionNoncePrefix || header.ChunkSize <= 0 || header.ChunkSize > 16*encryptionChunkSize {
+		return nil, fmt.Errorf("invalid encryption header")
+	}
+
+	kek, err := key.kek(&header)
+	if err != nil {
+		return nil, err
+	}
+	kekGCM, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.KeyNonce) != kekGCM.NonceSize() {
+		return nil, fmt.Errorf("invalid encryption header")
+	}
+	archiveKey, err := kekGCM.Open(nil, header.KeyNonce, header.WrappedKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or key")
+	}
+	aead, err := newGCM(archiveKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: br, aead: aead, noncePrefix: header.NoncePrefix, maxSealed: header.ChunkSize + aead.Overhead()}, nil
+}
@@ -0,0 +1,136 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptArchive(t *testing.T) {
+	key := make([]byte, encryptionKeySize)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+
+	// more than a couple of chunks with a partial last chunk
+	plaintext := make([]byte, 3*encryptionChunkSize+100)
+	_, err = rand.Read(plaintext)
+	assert.NoError(t, err)
+
+	for _, encryptionKey := range []*EncryptionKey{{Key: key}, {Passphrase: "correct horse battery staple"}} {
+		var buf bytes.Buffer
+		w, err := newEncryptWriter(&buf, encryptionKey)
+		assert.NoError(t, err)
+		_, err = w.Write(plaintext)
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+		assert.False(t, bytes.Contains(buf.Bytes(), plaintext[:64]))
+		encrypted := buf.Bytes()
+
+		r, err := openArchive(bytes.NewReader(encrypted), encryptionKey)
+		assert.NoError(t, err)
+		decrypted, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, plaintext, decrypted)
+
+		// a key is required
+		_, err = openArchive(bytes.NewReader(encrypted), nil)
+		assert.Error(t, err)
+
+		// wrong key
+		_, err = openArchive(bytes.NewReader(encrypted), &EncryptionKey{Key: make([]byte, encryptionKeySize), Passphrase: "wrong"})
+		assert.Error(t, err)
+
+		// truncated archive, whole chunks dropped or a partial chunk
+		for _, size := range []int{len(encrypted) - (encryptionChunkSize + 100), len(encrypted) - 10} {
+			r, err = openArchive(bytes.NewReader(encrypted[:size]), encryptionKey)
+			assert.NoError(t, err)
+			_, err = io.ReadAll(r)
+			assert.Error(t, err)
+		}
+
+		// tampered chunk
+		tampered := append([]byte{}, encrypted...)
+		tampered[len(tampered)-1] ^= 1
+		r, err = openArchive(bytes.NewReader(tampered), encryptionKey)
+		assert.NoError(t, err)
+		_, err = io.ReadAll(r)
+		assert.Error(t, err)
+	}
+
+	// empty archive
+	var buf bytes.Buffer
+	w, err := newEncryptWriter(&buf, &EncryptionKey{Key: key})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	r, err := openArchive(&buf, &EncryptionKey{Key: key})
+	assert.NoError(t, err)
+	decrypted, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Empty(t, decrypted)
+
+	// unknown formats
+	_, err = openArchive(strings.NewReader(`{"dashboards":[]}`), nil)
+	assert.Error(t, err)
+}
+
+func TestLoadEncryptionKey(t *testing.T) {
+	dir := t.TempDir()
+	passphraseFile := filepath.Join(dir, "passphrase")
+	assert.NoError(t, os.WriteFile(passphraseFile, []byte("secret\n"), 0600))
+	keyFile := filepath.Join(dir, "key")
+	assert.NoError(t, os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(make([]byte, 32))+"\n"), 0600))
+	shortKeyFile := filepath.Join(dir, "short-key")
+	assert.NoError(t, os.WriteFile(shortKeyFile, []byte(base64.StdEncoding.EncodeToString(make([]byte, 16))), 0600))
+
+	key, err := LoadEncryptionKey("", "")
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	key, err = LoadEncryptionKey(passphraseFile, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", key.Passphrase)
+
+	key, err = LoadEncryptionKey("", keyFile)
+	assert.NoError(t, err)
+	assert.Len(t, key.Key, 32)
+
+	_, err = LoadEncryptionKey("", shortKeyFile)
+	assert.Error(t, err)
+	_, err = LoadEncryptionKey(passphraseFile, keyFile)
+	assert.Error(t, err)
+}
+
+func TestImportEncryptedBackup(t *testing.T) {
+	source := newFakeGrafana(t)
+	source.orgs[1].addDatasource("prometheus", "prometheus")
+	source.orgs[1].addDashboard("dash", "Dash", nil)
+
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	assert.NoError(t, os.WriteFile(passphraseFile, []byte("secret"), 0600))
+
+	src := backupFile(t, source, BackupOptions{EncryptionKey: &EncryptionKey{Passphrase: "secret"}})
+	assert.True(t, strings.HasSuffix(src, ".json.gz.enc"))
+	by, err := os.ReadFile(src)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(by, []byte("prometheus")))
+
+	// the archive name still follows the backup naming scheme
+	archives, err := listBackupArchives(context.Background(), NewLocalBackupStore(filepath.Dir(src)), "")
+	assert.NoError(t, err)
+	assert.Len(t, archives, 1)
+
+	target := newFakeGrafana(t)
+	assert.Error(t, runImport(t, target, "-src", src))
+	t.Setenv("GRAFCTL_ENCRYPTION_PASSPHRASE_FILE", passphraseFile)
+	assert.NoError(t, runImport(t, target, "-src", src))
+	assert.NotNil(t, target.orgs[1].dashboard("dash"))
+	assert.Equal(t, "prometheus", target.orgs[1].datasources[0].Name)
+}
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// importFailure is a resource import -continue-on-error failed to restore
+type importFailure struct {
+	Org   string `json:"org,omitempty"`
+	Kind  string `json:"kind"`
+	UID   string `json:"uid,omitempty"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// importFailures collects the failures of an import continuing past failed resources
+type importFailures struct {
+	failures []*importFailure
+}
+
+func (f *importFailures) add(failure *importFailure) {
+	f.failures = append(f.failures, failure)
+}
+
+// print prints a table of the failures
+func (f *importFailures) print(w io.Writer) {
+	fmt.Fprintf(w, "Failures: %d\n", len(f.failures))
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Org", "Kind", "UID", "Name", "Error"})
+	table.SetAutoWrapText(false)
+	for _, failure := range f.failures {
+		table.Append([]string{failure.Org, failure.Kind, failure.UID, failure.Name, failure.Error})
+	}
+	table.Render()
+}
+
+// writeReport writes the failures as a JSON report, the report is written even without failures
+func (f *importFailures) writeReport(path string) error {
+	by, err := json.MarshalIndent(struct {
+		Failures []*importFailure `json:"failures"`
+	}{Failures: append([]*importFailure{}, f.failures...)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(by, '\n'), 0644); err != nil {
+		return fmt.Errorf("write report %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportContinueOnError(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.addDatasource("prometheus", "prometheus")
+	sourceOrg.addDatasource("postgres", "postgres")
+	sourceOrg.addDashboard("main-dash", "Main", sourceOrg.addFolder("main-folder", "Main Folder"))
+	sourceOrg.addDashboard("broken-dash", "Broken", sourceOrg.addFolder("broken-folder", "Broken Folder"))
+	sourceOrg.addDashboard("failing-dash", "Failing", nil)
+	sourceOrg.addDashboard("general-dash", "General", nil)
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	target.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodPost {
+			return false
+		}
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		for _, name := range []string{`"postgres"`, `"Broken Folder"`, `"failing-dash"`} {
+			if strings.Contains(string(body), name) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid " + name})
+				return true
+			}
+		}
+		return false
+	}
+
+	report := filepath.Join(t.TempDir(), "report.json")
+	err := runImport(t, target, "-src", src, "-continue-on-error", "-report", report)
+	assert.EqualError(t, err, "import finished with 4 failure(s)")
+
+	// every other resource is restored
+	assert.Len(t, targetOrg.datasources, 1)
+	assert.Len(t, targetOrg.folders, 1)
+	assert.NotNil(t, targetOrg.dashboard("main-dash"))
+	assert.NotNil(t, targetOrg.dashboard("general-dash"))
+	assert.Nil(t, targetOrg.dashboard("broken-dash"))
+
+	by, err := os.ReadFile(report)
+	assert.NoError(t, err)
+	failures := struct {
+		Failures []*importFailure `json:"failures"`
+	}{}
+	assert.NoError(t, json.Unmarshal(by, &failures))
+	assert.Len(t, failures.Failures, 4)
+	identities := []string{}
+	for _, failure := range failures.Failures {
+		identities = append(identities, failure.Kind+" "+failure.UID+" "+failure.Name)
+	}
+	assert.Equal(t, []string{
+		"datasource postgres-uid postgres",
+		"folder broken-folder Broken Folder",
+		"dashboard broken-dash Broken",
+		"dashboard failing-dash Failing",
+	}, identities)
+	assert.Contains(t, failures.Failures[2].Error, "CreateFolder broken-folder Broken Folder")
+
+	assert.EqualError(t, runImport(t, target, "-src", src, "-continue-on-error", "-rollback"), "-rollback and -continue-on-error are mutually exclusive")
+}
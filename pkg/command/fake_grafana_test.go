@@ -0,0 +1,727 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/diogogmt/grafctl/pkg/simplejson"
+)
+
+// fakeGrafana is an in-memory grafana serving the subset of the HTTP API used by grafctl
+type fakeGrafana struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	orgs map[int64]*fakeOrg
+	// logins has the ids of the grafana users, users are added to orgs by login
+	logins map[string]int64
+	// intercept runs before the default handling of every request, eg; to inject latency or errors.
+	// The request is not handled any further when it returns true.
+	intercept func(w http.ResponseWriter, r *http.Request) bool
+}
+
+type fakeOrg struct {
+	org         *grafsdk.Org
+	datasources []*grafsdk.Datasource
+	// datasourceSecrets map the datasource names to the secrets grafana stores encrypted and never returns
+	datasourceSecrets map[string]map[string]string
+	folders           []*grafsdk.Folder
+	// flatFolders lists the top-level folders for every parent uid like grafana versions without nested folders
+	flatFolders bool
+	dashboards  []*grafsdk.DashboardWithMeta
+	// libraryElements is nil for orgs of grafana versions without library panels
+	libraryElements []*grafsdk.LibraryElement
+	// alerting is nil for orgs without the alerting provisioning API
+	alerting *fakeAlerting
+	// permissions map the folder and dashboard uids to their permissions, nil maps are served as forbidden
+	folderPermissions    map[string][]*grafsdk.Permission
+	dashboardPermissions map[string][]*grafsdk.Permission
+	teams                []*grafsdk.Team
+	teamMembers          map[int64][]int64
+	users                []*grafsdk.OrgUser
+}
+
+type fakeAlerting struct {
+	ruleGroups    []*grafsdk.AlertRuleGroup
+	contactPoints []*grafsdk.ContactPoint
+	policies      *simplejson.Json
+	muteTimings   []*grafsdk.MuteTiming
+	templates     []*grafsdk.NotificationTemplate
+	// decrypt serves the decrypted contact points export, it is forbidden like for credentials without the admin role
+	// otherwise
+	decrypt bool
+}
+
+// fakeSecureSettings are the contact point settings grafana redacts when listing contact points
+var fakeSecureSettings = map[string]bool{"url": true, "password": true, "token": true}
+
+func newFakeGrafana(t *testing.T) *fakeGrafana {
+	g := &fakeGrafana{
+		orgs:   map[int64]*fakeOrg{},
+		logins: map[string]int64{},
+	}
+	g.addOrg(1, "Main Org.")
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serveHTTP))
+	t.Cleanup(g.Close)
+	return g
+}
+
+func (g *fakeGrafana) addOrg(id int64, name string) *fakeOrg {
+	org := &fakeOrg{org: &grafsdk.Org{ID: id, Name: name}, teamMembers: map[int64][]int64{}}
+	g.orgs[id] = org
+	return org
+}
+
+func (g *fakeGrafana) client() *Client {
+	return NewClient(g.URL, "test-key", false)
+}
+
+// storeSecrets moves the secrets out of a datasource like grafana, only the secureJsonFields set are returned
+func (o *fakeOrg) storeSecrets(datasource *grafsdk.Datasource) {
+	secrets := map[string]string{}
+	for field, value := range datasource.SecureJsonData {
+		secrets[field] = value
+	}
+	if datasource.Password != "" {
+		secrets["password"] = datasource.Password
+	}
+	if datasource.BasicAuthPassword != "" {
+		secrets["basicAuthPassword"] = datasource.BasicAuthPassword
+	}
+	datasource.SecureJsonData, datasource.Password, datasource.BasicAuthPassword = nil, "", ""
+	if len(secrets) == 0 {
+		return
+	}
+	if o.datasourceSecrets == nil {
+		o.datasourceSecrets = map[string]map[string]string{}
+	}
+	o.datasourceSecrets[datasource.Name] = secrets
+	datasource.SecureJsonFields = map[string]bool{}
+	for field := range secrets {
+		datasource.SecureJsonFields[field] = true
+	}
+}
+
+func (o *fakeOrg) addDatasource(name string, dsType string) *grafsdk.Datasource {
+	datasource := &grafsdk.Datasource{ID: int64(len(o.datasources) + 1), UID: fmt.Sprintf("%s-uid", name), OrgID: o.org.ID, Name: name, Type: dsType}
+	o.datasources = append(o.datasources, datasource)
+	return datasource
+}
+
+func (o *fakeOrg) addFolder(uid string, title string) *grafsdk.Folder {
+	folder := &grafsdk.Folder{ID: int64(100*o.org.ID) + int64(len(o.folders)+1), UID: uid, Title: title, Version: 1}
+	o.folders = append(o.folders, folder)
+	return folder
+}
+
+func (o *fakeOrg) addSubfolder(uid string, title string, parent *grafsdk.Folder) *grafsdk.Folder {
+	folder := o.addFolder(uid, title)
+	folder.ParentUID = parent.UID
+	return folder
+}
+
+func (o *fakeOrg) addDashboard(uid string, title string, folder *grafsdk.Folder) *grafsdk.DashboardWithMeta {
+	dashboard := simplejson.New()
+	dashboard.Set("id", 1000*o.org.ID+int64(len(o.dashboards)+1))
+	dashboard.Set("uid", uid)
+	dashboard.Set("title", title)
+	dashboard.Set("version", 1)
+	dashboard.Set("panels", []interface{}{})
+	return o.saveDashboard(dashboard, folder)
+}
+
+// deleteFolder deletes the folder with the uid and its dashboards like grafana
+func (o *fakeOrg) deleteFolder(uid string) {
+	folders := []*grafsdk.Folder{}
+	for _, folder := range o.folders {
+		if folder.UID != uid {
+			folders = append(folders, folder)
+		}
+	}
+	o.folders = folders
+	dashboards := []*grafsdk.DashboardWithMeta{}
+	for _, dashboard := range o.dashboards {
+		if dashboard.Meta.Get("folderUid").MustString() != uid {
+			dashboards = append(dashboards, dashboard)
+		}
+	}
+	o.dashboards = dashboards
+}
+
+// saveDashboard creates or replaces the dashboard with the same uid
+func (o *fakeOrg) saveDashboard(dashboard *simplejson.Json, folder *grafsdk.Folder) *grafsdk.DashboardWithMeta {
+	meta := simplejson.New()
+	if folder != nil {
+		meta.Set("folderId", folder.ID)
+		meta.Set("folderUid", folder.UID)
+		meta.Set("folderTitle", folder.Title)
+	} else {
+		meta.Set("folderId", 0)
+		meta.Set("folderTitle", "General")
+	}
+	dashboardFull := &grafsdk.DashboardWithMeta{Meta: meta, Dashboard: dashboard}
+	if existing := o.dashboard(dashboard.Get("uid").MustString()); existing != nil {
+		*existing = *dashboardFull
+		return existing
+	}
+	o.dashboards = append(o.dashboards, dashboardFull)
+	return dashboardFull
+}
+
+func (o *fakeOrg) dashboard(uid string) *grafsdk.DashboardWithMeta {
+	for _, dashboard := range o.dashboards {
+		if dashboard.Dashboard.Get("uid").MustString() == uid {
+			return dashboard
+		}
+	}
+	return nil
+}
+
+func (g *fakeGrafana) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.intercept != nil && g.intercept(w, r) {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	orgID := int64(1)
+	if v := r.Header.Get("X-Grafana-Org-Id"); v != "" {
+		orgID, _ = strconv.ParseInt(v, 10, 64)
+	}
+	org, ok := g.orgs[orgID]
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "user is not a member of the organization"})
+		return
+	}
+
+	switch path := r.URL.Path; {
+	case path == "/api/health":
+		writeJSON(w, http.StatusOK, grafsdk.Health{Commit: "abc123", Database: "ok", Version: "9.5.0"})
+	case path == "/api/org":
+		writeJSON(w, http.StatusOK, org.org)
+	case path == "/api/orgs" && r.Method == http.MethodGet:
+		orgs := []*grafsdk.Org{}
+		for id := int64(1); id <= int64(len(g.orgs)); id++ {
+			orgs = append(orgs, g.orgs[id].org)
+		}
+		writeJSON(w, http.StatusOK, orgs)
+	case path == "/api/orgs" && r.Method == http.MethodPost:
+		payload := grafsdk.Org{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		newOrg := g.addOrg(int64(len(g.orgs)+1), payload.Name)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"orgId": newOrg.org.ID})
+	case strings.HasPrefix(path, "/api/orgs/name/"):
+		name := strings.TrimPrefix(path, "/api/orgs/name/")
+		for _, o := range g.orgs {
+			if o.org.Name == name {
+				writeJSON(w, http.StatusOK, o.org)
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Organization not found"})
+	case path == "/api/datasources" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, org.datasources)
+	case path == "/api/datasources" && r.Method == http.MethodPost:
+		datasource := &grafsdk.Datasource{}
+		json.NewDecoder(r.Body).Decode(datasource)
+		datasource.ID = int64(len(org.datasources) + 1)
+		datasource.OrgID = org.org.ID
+		org.storeSecrets(datasource)
+		org.datasources = append(org.datasources, datasource)
+		writeJSON(w, http.StatusOK, datasource)
+	case strings.HasPrefix(path, "/api/datasources/name/") && r.Method == http.MethodDelete:
+		name := strings.TrimPrefix(path, "/api/datasources/name/")
+		for i, datasource := range org.datasources {
+			if datasource.Name == name {
+				org.datasources = append(org.datasources[:i], org.datasources[i+1:]...)
+				writeJSON(w, http.StatusOK, map[string]string{"message": "Data source deleted"})
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Data source not found"})
+	case strings.HasPrefix(path, "/api/datasources/name/"):
+		name := strings.TrimPrefix(path, "/api/datasources/name/")
+		for _, datasource := range org.datasources {
+			if datasource.Name == name {
+				writeJSON(w, http.StatusOK, datasource)
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Data source not found"})
+	case strings.HasPrefix(path, "/api/datasources/") && r.Method == http.MethodPut:
+		datasource := &grafsdk.Datasource{}
+		json.NewDecoder(r.Body).Decode(datasource)
+		org.storeSecrets(datasource)
+		for i, existing := range org.datasources {
+			if existing.ID == datasource.ID {
+				org.datasources[i] = datasource
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Datasource updated"})
+	case path == "/api/folders" && r.Method == http.MethodGet:
+		// like grafana with nested folders only the children of the parent uid are listed, grafana versions without
+		// nested folders ignore the parent uid
+		folders := []*grafsdk.Folder{}
+		for _, folder := range org.folders {
+			if folder.ParentUID == r.URL.Query().Get("parentUid") || (org.flatFolders && folder.ParentUID == "") {
+				folders = append(folders, folder)
+			}
+		}
+		writeJSON(w, http.StatusOK, folders)
+	case path == "/api/folders" && r.Method == http.MethodPost:
+		payload := grafsdk.Folder{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		uid := payload.UID
+		if uid == "" {
+			uid = fmt.Sprintf("folder-%d", len(org.folders)+1)
+		}
+		folder := org.addFolder(uid, payload.Title)
+		folder.ParentUID = payload.ParentUID
+		writeJSON(w, http.StatusOK, folder)
+	case path == "/api/search":
+		g.search(w, r, org)
+	case strings.HasPrefix(path, "/api/library-elements"):
+		g.libraryElements(w, r, org)
+	case strings.HasPrefix(path, "/api/v1/provisioning/"):
+		g.provisioning(w, r, org)
+	case strings.HasPrefix(path, "/api/folders/") && strings.HasSuffix(path, "/permissions"):
+		g.permissions(w, r, org, org.folderPermissions, strings.TrimSuffix(strings.TrimPrefix(path, "/api/folders/"), "/permissions"))
+	case strings.HasPrefix(path, "/api/dashboards/uid/") && strings.HasSuffix(path, "/permissions"):
+		g.permissions(w, r, org, org.dashboardPermissions, strings.TrimSuffix(strings.TrimPrefix(path, "/api/dashboards/uid/"), "/permissions"))
+	case path == "/api/teams/search":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"totalCount": len(org.teams), "teams": org.teams})
+	case path == "/api/teams" && r.Method == http.MethodPost:
+		payload := grafsdk.Team{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		team := org.addTeam(int64(100+len(org.teams)), payload.Name)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"teamId": team.ID, "message": "Team created"})
+	case strings.HasPrefix(path, "/api/teams/") && strings.HasSuffix(path, "/members"):
+		teamID, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(path, "/api/teams/"), "/members"), 10, 64)
+		if r.Method == http.MethodPost {
+			payload := struct {
+				UserID int64 `json:"userId"`
+			}{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			org.teamMembers[teamID] = append(org.teamMembers[teamID], payload.UserID)
+			writeJSON(w, http.StatusOK, map[string]string{"message": "Member added to Team"})
+			return
+		}
+		members := []*grafsdk.TeamMember{}
+		for _, userID := range org.teamMembers[teamID] {
+			for _, user := range org.users {
+				if user.UserID == userID {
+					members = append(members, &grafsdk.TeamMember{UserID: userID, TeamID: teamID, Login: user.Login, Email: user.Email})
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, members)
+	case path == "/api/org/users" && r.Method == http.MethodPost:
+		payload := struct {
+			LoginOrEmail string `json:"loginOrEmail"`
+			Role         string `json:"role"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		userID, ok := g.logins[payload.LoginOrEmail]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "User not found"})
+			return
+		}
+		org.addUser(userID, payload.LoginOrEmail).Role = payload.Role
+		writeJSON(w, http.StatusOK, map[string]interface{}{"userId": userID, "message": "User added to organization"})
+	case path == "/api/org/users":
+		writeJSON(w, http.StatusOK, org.users)
+	case strings.HasPrefix(path, "/api/folders/") && r.Method == http.MethodDelete:
+		org.deleteFolder(strings.TrimPrefix(path, "/api/folders/"))
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Folder deleted"})
+	case strings.HasPrefix(path, "/api/dashboards/uid/") && r.Method == http.MethodDelete:
+		uid := strings.TrimPrefix(path, "/api/dashboards/uid/")
+		for i, dashboard := range org.dashboards {
+			if dashboard.Dashboard.Get("uid").MustString() == uid {
+				org.dashboards = append(org.dashboards[:i], org.dashboards[i+1:]...)
+				writeJSON(w, http.StatusOK, map[string]string{"message": "Dashboard deleted"})
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Dashboard not found"})
+	case strings.HasPrefix(path, "/api/dashboards/uid/"):
+		dashboard := org.dashboard(strings.TrimPrefix(path, "/api/dashboards/uid/"))
+		if dashboard == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Dashboard not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, dashboard)
+	case path == "/api/dashboards/db":
+		payload := grafsdk.DashboardSavePayload{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		var folder *grafsdk.Folder
+		for _, f := range org.folders {
+			if (payload.FolderUID != "" && f.UID == payload.FolderUID) || (payload.FolderID != 0 && f.ID == payload.FolderID) {
+				folder = f
+			}
+		}
+		// like grafana every save raises the version
+		version := int64(1)
+		if existing := org.dashboard(payload.Dashboard.Get("uid").MustString()); existing != nil {
+			version = existing.Dashboard.Get("version").MustInt64() + 1
+		}
+		payload.Dashboard.Set("version", version)
+		org.saveDashboard(payload.Dashboard, folder)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"uid": payload.Dashboard.Get("uid").MustString(), "status": "success"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not found"})
+	}
+}
+
+// enablePermissions serves the folder and dashboard permissions, every resource starts with the default role permissions
+func (o *fakeOrg) enablePermissions() {
+	o.folderPermissions = map[string][]*grafsdk.Permission{}
+	o.dashboardPermissions = map[string][]*grafsdk.Permission{}
+}
+
+func (o *fakeOrg) addTeam(id int64, name string) *grafsdk.Team {
+	team := &grafsdk.Team{ID: id, OrgID: o.org.ID, Name: name}
+	o.teams = append(o.teams, team)
+	return team
+}
+
+func (o *fakeOrg) addTeamMember(team *grafsdk.Team, user *grafsdk.OrgUser) {
+	o.teamMembers[team.ID] = append(o.teamMembers[team.ID], user.UserID)
+}
+
+func (o *fakeOrg) addUser(id int64, login string) *grafsdk.OrgUser {
+	user := &grafsdk.OrgUser{UserID: id, Login: login, Email: login + "@example.com", Role: "Viewer"}
+	o.users = append(o.users, user)
+	return user
+}
+
+// permissions serves the permissions of a folder or dashboard, updates replace every permission like grafana
+func (g *fakeGrafana) permissions(w http.ResponseWriter, r *http.Request, org *fakeOrg, resourcePermissions map[string][]*grafsdk.Permission, uid string) {
+	if resourcePermissions == nil {
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": "Permission denied"})
+		return
+	}
+	if r.Method == http.MethodGet {
+		permissions, ok := resourcePermissions[uid]
+		if !ok {
+			permissions = []*grafsdk.Permission{{Role: "Viewer", Permission: grafsdk.PermissionView}, {Role: "Editor", Permission: grafsdk.PermissionEdit}}
+		}
+		writeJSON(w, http.StatusOK, permissions)
+		return
+	}
+
+	payload := struct {
+		Items []*grafsdk.PermissionItem `json:"items"`
+	}{}
+	json.NewDecoder(r.Body).Decode(&payload)
+	permissions := []*grafsdk.Permission{}
+	for _, item := range payload.Items {
+		permission := &grafsdk.Permission{UserID: item.UserID, TeamID: item.TeamID, Role: item.Role, Permission: item.Permission}
+		for _, user := range org.users {
+			if user.UserID == item.UserID {
+				permission.UserLogin = user.Login
+			}
+		}
+		for _, team := range org.teams {
+			if team.ID == item.TeamID {
+				permission.Team = team.Name
+			}
+		}
+		permissions = append(permissions, permission)
+	}
+	resourcePermissions[uid] = permissions
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Permissions updated"})
+}
+
+func (o *fakeOrg) addLibraryPanel(uid string, name string, folder *grafsdk.Folder) *grafsdk.LibraryElement {
+	model := simplejson.New()
+	model.Set("type", "timeseries")
+	model.Set("title", name)
+	element := &grafsdk.LibraryElement{ID: int64(len(o.libraryElements) + 1), UID: uid, Name: name, Kind: grafsdk.LibraryPanelKind, Model: model, Version: 1}
+	if folder != nil {
+		element.FolderID = folder.ID
+		element.FolderUID = folder.UID
+	}
+	o.libraryElements = append(o.libraryElements, element)
+	return element
+}
+
+func (o *fakeOrg) libraryElement(uid string) *grafsdk.LibraryElement {
+	for _, element := range o.libraryElements {
+		if element.UID == uid {
+			return element
+		}
+	}
+	return nil
+}
+
+// libraryElements serves the library elements API, updates must send the current version like grafana
+func (g *fakeGrafana) libraryElements(w http.ResponseWriter, r *http.Request, org *fakeOrg) {
+	if org.libraryElements == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not found"})
+		return
+	}
+	uid := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/library-elements"), "/")
+	switch {
+	case uid == "" && r.Method == http.MethodGet:
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("perPage"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := (page - 1) * perPage
+		if start > len(org.libraryElements) {
+			start = len(org.libraryElements)
+		}
+		end := start + perPage
+		if end > len(org.libraryElements) {
+			end = len(org.libraryElements)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"result": map[string]interface{}{"totalCount": len(org.libraryElements), "elements": org.libraryElements[start:end]},
+		})
+	case uid == "" && r.Method == http.MethodPost:
+		element := &grafsdk.LibraryElement{}
+		json.NewDecoder(r.Body).Decode(element)
+		element.ID = int64(len(org.libraryElements) + 1)
+		element.Version = 1
+		org.libraryElements = append(org.libraryElements, element)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": element})
+	case r.Method == http.MethodGet:
+		element := org.libraryElement(uid)
+		if element == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "library element could not be found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": element})
+	case r.Method == http.MethodPatch:
+		existing := org.libraryElement(uid)
+		element := &grafsdk.LibraryElement{}
+		json.NewDecoder(r.Body).Decode(element)
+		if existing == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "library element could not be found"})
+			return
+		}
+		if element.Version != existing.Version {
+			writeJSON(w, http.StatusPreconditionFailed, map[string]string{"message": "the library element has been changed by someone else"})
+			return
+		}
+		element.ID = existing.ID
+		element.Version = existing.Version + 1
+		*existing = *element
+		writeJSON(w, http.StatusOK, map[string]interface{}{"result": element})
+	case r.Method == http.MethodDelete:
+		for i, element := range org.libraryElements {
+			if element.UID == uid {
+				org.libraryElements = append(org.libraryElements[:i], org.libraryElements[i+1:]...)
+				writeJSON(w, http.StatusOK, map[string]string{"message": "Library element deleted"})
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "library element could not be found"})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not found"})
+	}
+}
+
+// enableAlerting serves the alerting provisioning API for the org with the default notification policy
+func (o *fakeOrg) enableAlerting() *fakeAlerting {
+	policies := simplejson.New()
+	policies.Set("receiver", "grafana-default-email")
+	o.alerting = &fakeAlerting{policies: policies}
+	return o.alerting
+}
+
+func (o *fakeOrg) addAlertRuleGroup(folder *grafsdk.Folder, title string, ruleUIDs ...string) *grafsdk.AlertRuleGroup {
+	ruleGroup := &grafsdk.AlertRuleGroup{Title: title, FolderUID: folder.UID, Interval: 60}
+	for _, uid := range ruleUIDs {
+		rule := simplejson.New()
+		rule.Set("uid", uid)
+		rule.Set("title", uid)
+		rule.Set("folderUID", folder.UID)
+		rule.Set("ruleGroup", title)
+		rule.Set("condition", "A")
+		ruleGroup.Rules = append(ruleGroup.Rules, rule)
+	}
+	o.alerting.ruleGroups = append(o.alerting.ruleGroups, ruleGroup)
+	return ruleGroup
+}
+
+// provisioning serves the alerting provisioning API, writes without the X-Disable-Provenance header are rejected
+// since the restored resources would not be editable in the UI
+func (g *fakeGrafana) provisioning(w http.ResponseWriter, r *http.Request, org *fakeOrg) {
+	alerting := org.alerting
+	if alerting == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not found"})
+		return
+	}
+	if r.Method != http.MethodGet && r.Header.Get("X-Disable-Provenance") != "true" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "missing X-Disable-Provenance"})
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/provisioning/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "alert-rules":
+		rules := []*simplejson.Json{}
+		for _, ruleGroup := range alerting.ruleGroups {
+			rules = append(rules, ruleGroup.Rules...)
+		}
+		writeJSON(w, http.StatusOK, rules)
+	case len(parts) == 4 && parts[0] == "folder" && parts[2] == "rule-groups":
+		if r.Method == http.MethodPut {
+			ruleGroup := &grafsdk.AlertRuleGroup{}
+			json.NewDecoder(r.Body).Decode(ruleGroup)
+			ruleGroup.FolderUID, ruleGroup.Title = parts[1], parts[3]
+			for i, existing := range alerting.ruleGroups {
+				if existing.FolderUID == ruleGroup.FolderUID && existing.Title == ruleGroup.Title {
+					alerting.ruleGroups[i] = ruleGroup
+					writeJSON(w, http.StatusOK, ruleGroup)
+					return
+				}
+			}
+			alerting.ruleGroups = append(alerting.ruleGroups, ruleGroup)
+			writeJSON(w, http.StatusOK, ruleGroup)
+			return
+		}
+		for _, ruleGroup := range alerting.ruleGroups {
+			if ruleGroup.FolderUID == parts[1] && ruleGroup.Title == parts[3] {
+				writeJSON(w, http.StatusOK, ruleGroup)
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "rule group not found"})
+	case path == "contact-points" && r.Method == http.MethodGet:
+		contactPoints := []*grafsdk.ContactPoint{}
+		for _, contactPoint := range alerting.contactPoints {
+			redacted := *contactPoint
+			if contactPoint.Settings != nil {
+				redacted.Settings = simplejson.New()
+				for key, value := range contactPoint.Settings.MustMap() {
+					if fakeSecureSettings[key] {
+						value = redactedValue
+					}
+					redacted.Settings.Set(key, value)
+				}
+			}
+			contactPoints = append(contactPoints, &redacted)
+		}
+		writeJSON(w, http.StatusOK, contactPoints)
+	case path == "contact-points/export":
+		if !alerting.decrypt || r.URL.Query().Get("decrypt") != "true" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"message": "permissions needed"})
+			return
+		}
+		export := map[string][]interface{}{}
+		names := []string{}
+		for _, contactPoint := range alerting.contactPoints {
+			if _, ok := export[contactPoint.Name]; !ok {
+				names = append(names, contactPoint.Name)
+			}
+			export[contactPoint.Name] = append(export[contactPoint.Name], map[string]interface{}{
+				"uid": contactPoint.UID, "type": contactPoint.Type, "settings": contactPoint.Settings, "disableResolveMessage": contactPoint.DisableResolveMessage,
+			})
+		}
+		groups := []interface{}{}
+		for _, name := range names {
+			groups = append(groups, map[string]interface{}{"orgId": org.org.ID, "name": name, "receivers": export[name]})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"apiVersion": 1, "contactPoints": groups})
+	case path == "contact-points" && r.Method == http.MethodPost:
+		contactPoint := &grafsdk.ContactPoint{}
+		json.NewDecoder(r.Body).Decode(contactPoint)
+		alerting.contactPoints = append(alerting.contactPoints, contactPoint)
+		writeJSON(w, http.StatusAccepted, contactPoint)
+	case parts[0] == "contact-points" && len(parts) == 2 && r.Method == http.MethodPut:
+		contactPoint := &grafsdk.ContactPoint{}
+		json.NewDecoder(r.Body).Decode(contactPoint)
+		for i, existing := range alerting.contactPoints {
+			if existing.UID == parts[1] {
+				alerting.contactPoints[i] = contactPoint
+			}
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": "contactpoint updated"})
+	case path == "policies" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, alerting.policies)
+	case path == "policies" && r.Method == http.MethodPut:
+		policies := simplejson.New()
+		json.NewDecoder(r.Body).Decode(policies)
+		alerting.policies = policies
+		writeJSON(w, http.StatusAccepted, map[string]string{"message": "policies updated"})
+	case path == "mute-timings" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, alerting.muteTimings)
+	case path == "mute-timings" && r.Method == http.MethodPost:
+		muteTiming := &grafsdk.MuteTiming{}
+		json.NewDecoder(r.Body).Decode(muteTiming)
+		alerting.muteTimings = append(alerting.muteTimings, muteTiming)
+		writeJSON(w, http.StatusCreated, muteTiming)
+	case parts[0] == "mute-timings" && len(parts) == 2 && r.Method == http.MethodPut:
+		muteTiming := &grafsdk.MuteTiming{}
+		json.NewDecoder(r.Body).Decode(muteTiming)
+		for i, existing := range alerting.muteTimings {
+			if existing.Name == parts[1] {
+				alerting.muteTimings[i] = muteTiming
+			}
+		}
+		writeJSON(w, http.StatusOK, muteTiming)
+	case path == "templates":
+		writeJSON(w, http.StatusOK, alerting.templates)
+	case parts[0] == "templates" && len(parts) == 2 && r.Method == http.MethodPut:
+		template := &grafsdk.NotificationTemplate{}
+		json.NewDecoder(r.Body).Decode(template)
+		template.Name = parts[1]
+		for i, existing := range alerting.templates {
+			if existing.Name == template.Name {
+				alerting.templates[i] = template
+				writeJSON(w, http.StatusAccepted, template)
+				return
+			}
+		}
+		alerting.templates = append(alerting.templates, template)
+		writeJSON(w, http.StatusAccepted, template)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not found"})
+	}
+}
+
+func (g *fakeGrafana) search(w http.ResponseWriter, r *http.Request, org *fakeOrg) {
+	results := []*grafsdk.SearchResult{}
+	for _, dashboard := range org.dashboards {
+		results = append(results, &grafsdk.SearchResult{
+			UID:         dashboard.Dashboard.Get("uid").MustString(),
+			Title:       dashboard.Dashboard.Get("title").MustString(),
+			Type:        grafsdk.DashHitDB,
+			FolderUID:   dashboard.Meta.Get("folderUid").MustString(),
+			FolderTitle: dashboard.Meta.Get("folderTitle").MustString(),
+		})
+	}
+	// paginate like grafana, a page past the end is empty
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 1000
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	start := (page - 1) * limit
+	if start > len(results) {
+		start = len(results)
+	}
+	end := start + limit
+	if end > len(results) {
+		end = len(results)
+	}
+	writeJSON(w, http.StatusOK, results[start:end])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// filter kinds of the import -include and -exclude flags
+const (
+	filterFolder     = "folder"
+	filterDashboard  = "dashboard"
+	filterTag        = "tag"
+	filterDatasource = "datasource"
+)
+
+// filterRule matches the folder titles, dashboard uids, dashboard tags or datasource names with a glob pattern
+type filterRule struct {
+	kind    string
+	pattern string
+}
+
+func (r filterRule) match(kind string, values ...string) bool {
+	if r.kind != kind {
+		return false
+	}
+	for _, value := range values {
+		if ok, _ := path.Match(r.pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// importFilter selects the resources of a backup restored by import.
+// Without includes every resource is selected, otherwise only the resources matching an include are.
+// Excludes always win over includes.
+type importFilter struct {
+	includes []filterRule
+	excludes []filterRule
+}
+
+// parseImportFilter parses the kind:pattern rules of the -include and -exclude flags, eg; folder:Team*
+func parseImportFilter(includes []string, excludes []string) (*importFilter, error) {
+	filter := importFilter{}
+	for _, include := range includes {
+		rule, err := parseFilterRule(include)
+		if err != nil {
+			return nil, fmt.Errorf("-include: %w", err)
+		}
+		filter.includes = append(filter.includes, rule)
+	}
+	for _, exclude := range excludes {
+		rule, err := parseFilterRule(exclude)
+		if err != nil {
+			return nil, fmt.Errorf("-exclude: %w", err)
+		}
+		filter.excludes = append(filter.excludes, rule)
+	}
+	return &filter, nil
+}
+
+func parseFilterRule(value string) (filterRule, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return filterRule{}, fmt.Errorf("invalid filter %q, expected kind:pattern, eg; folder:Team*", value)
+	}
+	rule := filterRule{kind: parts[0], pattern: parts[1]}
+	switch rule.kind {
+	case filterFolder, filterDashboard, filterTag, filterDatasource:
+	default:
+		return filterRule{}, fmt.Errorf("invalid filter %q, expected kind folder, dashboard, tag or datasource", value)
+	}
+	if _, err := path.Match(rule.pattern, ""); err != nil {
+		return filterRule{}, fmt.Errorf("invalid filter %q: %w", value, err)
+	}
+	return rule, nil
+}
+
+// selective reports whether only the included resources are restored
+func (f *importFilter) selective() bool {
+	return len(f.includes) > 0
+}
+
+// selectsDashboards reports whether the filter includes dashboards by rule, folders are then only created when a
+// selected dashboard needs them. Excludes alone restore every folder like a full import.
+func (f *importFilter) selectsDashboards() bool {
+	for _, rule := range f.includes {
+		if rule.kind != filterDatasource {
+			return true
+		}
+	}
+	return false
+}
+
+// datasource reports whether the datasource with the name is selected
+func (f *importFilter) datasource(name string) bool {
+	return f.selects(func(rule filterRule) bool {
+		return rule.match(filterDatasource, name)
+	})
+}
+
+// dashboard reports whether the dashboard with the uid, folder title and tags is selected
+func (f *importFilter) dashboard(uid string, folderTitle string, tags []string) bool {
+	return f.selects(func(rule filterRule) bool {
+		return rule.match(filterDashboard, uid) || rule.match(filterFolder, folderTitle) || rule.match(filterTag, tags...)
+	})
+}
+
+// folderExcluded reports whether the folder with the title is excluded
+func (f *importFilter) folderExcluded(title string) bool {
+	for _, rule := range f.excludes {
+		if rule.match(filterFolder, title) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *importFilter) selects(match func(rule filterRule) bool) bool {
+	for _, rule := range f.excludes {
+		if match(rule) {
+			return false
+		}
+	}
+	if !f.selective() {
+		return true
+	}
+	for _, rule := range f.includes {
+		if match(rule) {
+			return true
+		}
+	}
+	return false
+}
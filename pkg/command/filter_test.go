@@ -0,0 +1,48 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImportFilter(t *testing.T) {
+	filter, err := parseImportFilter([]string{"folder:Team*", "datasource:prom-?"}, []string{"tag:experimental"})
+	assert.NoError(t, err)
+	assert.True(t, filter.selective())
+	assert.True(t, filter.selectsDashboards())
+
+	for _, value := range []string{"Team*", "panel:cpu", "folder:", "dashboard:[a"} {
+		_, err := parseImportFilter([]string{value}, nil)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestImportFilter(t *testing.T) {
+	// without includes everything but the excludes is selected
+	filter, err := parseImportFilter(nil, []string{"tag:experimental", "datasource:test-*"})
+	assert.NoError(t, err)
+	assert.False(t, filter.selective())
+	assert.False(t, filter.selectsDashboards())
+	assert.True(t, filter.dashboard("main", "General", nil))
+	assert.False(t, filter.dashboard("main", "General", []string{"prod", "experimental"}))
+	assert.True(t, filter.datasource("prometheus"))
+	assert.False(t, filter.datasource("test-prometheus"))
+
+	// with includes only the matching resources are selected, excludes win
+	filter, err = parseImportFilter([]string{"folder:Team*", "dashboard:main"}, []string{"folder:Team B"})
+	assert.NoError(t, err)
+	assert.True(t, filter.dashboard("main", "General", nil))
+	assert.True(t, filter.dashboard("other", "Team A", nil))
+	assert.False(t, filter.dashboard("other", "Team B", nil))
+	assert.False(t, filter.dashboard("other", "General", nil))
+	assert.False(t, filter.datasource("prometheus"))
+	assert.True(t, filter.folderExcluded("Team B"))
+
+	// datasource rules alone keep folders eager and select no dashboard
+	filter, err = parseImportFilter([]string{"datasource:prometheus"}, nil)
+	assert.NoError(t, err)
+	assert.False(t, filter.selectsDashboards())
+	assert.False(t, filter.dashboard("main", "General", nil))
+	assert.True(t, filter.datasource("prometheus"))
+}
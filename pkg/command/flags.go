@@ -0,0 +1,17 @@
+package command
+
+import (
+	"strings"
+)
+
+// stringsFlag is a flag.Value collecting every occurrence of a repeatable flag
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
@@ -1,16 +1,14 @@
 package command
 
 import (
-	"compress/gzip"
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
+	"log"
 	"os"
 	"strings"
+	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/diogogmt/grafctl/pkg/grafsdk"
 	"github.com/diogogmt/grafctl/pkg/simplejson"
 	"github.com/peterbourgon/ff/v2/ffcli"
@@ -20,7 +18,15 @@ import (
 type ImportConfig struct {
 	*RootConfig
 
-	Src string
+	Src             string
+	Include         stringsFlag
+	Exclude         stringsFlag
+	DryRun          bool
+	Rollback        bool
+	ContinueOnError bool
+	Report          string
+	DSMap           string
+	Secrets         string
 }
 
 // ImportCmd wraps the dashboardImport config and a ffcli.Command
@@ -54,7 +60,15 @@ func NewImportCmd(rootConf *RootConfig) *ImportCmd {
 
 // RegisterFlags registers a set of flags for the dashboardImport command
 func (c *ImportCmd) RegisterFlags(fs *flag.FlagSet) {
-	fs.StringVar(&c.Conf.Src, "src", "", "location where to read the backup from; either the path to a local backup or the remote bucket URL, eg; gs://grafana-backup-bucket/monitoring-2020-12-20.json")
+	fs.StringVar(&c.Conf.Src, "src", "", "location where to read the backup from; either the path to a local backup or the remote object URL, eg; gs://grafana-backup-bucket/monitoring-2020-12-20.json or s3://grafana-backup-bucket/prefix/monitoring-2020-12-20.json")
+	fs.Var(&c.Conf.Include, "include", "only import the resources matching the kind:pattern glob, kinds are folder (title), dashboard (uid), tag and datasource (name), eg; folder:Team* (repeatable)")
+	fs.Var(&c.Conf.Exclude, "exclude", "skip the resources matching the kind:pattern glob, takes precedence over -include, eg; tag:experimental (repeatable)")
+	fs.BoolVar(&c.Conf.DryRun, "dry-run", false, "print the changes the import would make without making them, exits with code 2 when changes are pending")
+	fs.BoolVar(&c.Conf.Rollback, "rollback", false, "undo the datasource, folder, library panel and dashboard changes of a failed import, the other changes are reported as not rolled back")
+	fs.BoolVar(&c.Conf.ContinueOnError, "continue-on-error", false, "keep importing past failed datasources, folders and dashboards, the failures are reported at the end")
+	fs.StringVar(&c.Conf.DSMap, "ds-map", "", "YAML file mapping the name or uid of backup datasources to the name or uid of target datasources, datasources are mapped by name by default")
+	fs.StringVar(&c.Conf.Secrets, "secrets", "", "YAML file with the password, basicAuthPassword and secureJsonData of datasources keyed by name or uid, ${VAR} references are read from the environment")
+	fs.StringVar(&c.Conf.Report, "report", "", "file where to write the JSON report of the failures of -continue-on-error")
 }
 
 // Exec executes the dashboardImport command
@@ -62,144 +76,577 @@ func (c *ImportCmd) Exec(ctx context.Context, args []string) error {
 	if c.Conf.Src == "" {
 		return fmt.Errorf("missing -src")
 	}
-
-	var bucketName string
-	var objectName string
-	if strings.HasPrefix(c.Conf.Src, "gs://") {
-		parts := strings.Split(c.Conf.Src, "gs://")
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid gcs url")
+	if c.Conf.Rollback && c.Conf.ContinueOnError {
+		return fmt.Errorf("-rollback and -continue-on-error are mutually exclusive")
+	}
+	if c.Conf.Report != "" && !c.Conf.ContinueOnError {
+		return fmt.Errorf("-report requires -continue-on-error")
+	}
+	filter, err := parseImportFilter(c.Conf.Include, c.Conf.Exclude)
+	if err != nil {
+		return err
+	}
+	dsMap := map[string]string{}
+	if c.Conf.DSMap != "" {
+		if dsMap, err = loadDatasourceMap(c.Conf.DSMap); err != nil {
+			return err
 		}
-		parts = strings.Split(parts[1], "/")
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid gcs url")
+	}
+	var secrets datasourceSecrets
+	if c.Conf.Secrets != "" {
+		if secrets, err = loadDatasourceSecrets(c.Conf.Secrets); err != nil {
+			return err
 		}
-		bucketName = parts[0]
-		objectName = parts[1]
+	}
+	client, err := c.Conf.Client(ctx)
+	if err != nil {
+		return err
 	}
 
 	c.Conf.logd("reading backup from %q", c.Conf.Src)
 
-	var backupReader io.ReadCloser
-	if bucketName != "" {
-		gcsClient, err := storage.NewClient(ctx)
+	if c.Conf.DryRun {
+		planner := importPlanner{root: client, filter: filter, dsMap: dsMap, w: os.Stdout}
+		if _, err := c.Conf.readBackup(ctx, c.Conf.Src, &planner); err != nil {
+			return err
+		}
+		planner.summary()
+		if pending := planner.pending(); pending > 0 {
+			return &ExitError{Code: ExitChangesPending, Err: fmt.Errorf("%d change(s) pending", pending)}
+		}
+		return nil
+	}
+
+	// sections are restored while the archive is decoded, the whole backup is never held in memory
+	imp := importer{conf: c.Conf, root: client, filter: filter, dsMap: dsMap, secrets: secrets}
+	if c.Conf.Rollback {
+		imp.journal = &importJournal{}
+	}
+	if c.Conf.ContinueOnError {
+		imp.failures = &importFailures{}
+	}
+	manifest, err := c.Conf.readBackup(ctx, c.Conf.Src, &imp)
+	if err == nil && manifest != nil {
+		c.Conf.logd("imported backup of %s taken at %s with grafctl %s", manifest.Source, manifest.Created.Format(time.RFC3339), manifest.GrafctlVersion)
+	}
+	switch {
+	case err != nil && imp.journal != nil:
+		// the changes are undone even when the import was canceled
+		if rollbackErr := imp.journal.rollback(context.WithoutCancel(ctx), os.Stdout); rollbackErr != nil {
+			return fmt.Errorf("%w; %v", err, rollbackErr)
+		}
+		if partial := imp.journal.partial(); partial > 0 {
+			return fmt.Errorf("import partially rolled back, %d change(s) can't be rolled back: %w", partial, err)
+		}
+		return fmt.Errorf("import rolled back: %w", err)
+	case imp.failures != nil:
+		return c.Conf.reportFailures(imp.failures, err)
+	}
+	return err
+}
+
+// reportFailures prints the failures of an import continuing past failed resources and writes the -report file,
+// the import fails once every resource was attempted when any of them failed
+func (c *ImportConfig) reportFailures(failures *importFailures, err error) error {
+	if len(failures.failures) > 0 {
+		failures.print(os.Stdout)
+	}
+	if c.Report != "" {
+		if err := failures.writeReport(c.Report); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(failures.failures) > 0 {
+		return fmt.Errorf("import finished with %d failure(s)", len(failures.failures))
+	}
+	return nil
+}
+
+// importer is a backupVisitor restoring the selected resources of every section of a backup into grafana
+type importer struct {
+	conf     *ImportConfig
+	root     *Client
+	filter   *importFilter
+	dsMap    map[string]string
+	secrets  datasourceSecrets
+	journal  *importJournal
+	failures *importFailures
+
+	// client, folders and counters of the section being restored
+	client            *Client
+	org               string
+	principals        *principalResolver
+	remapper          *datasourceRemapper
+	backupFolders     []*grafsdk.Folder
+	targetFolders     []*grafsdk.Folder
+	restoredFolders   map[string]*grafsdk.Folder
+	failedFolders     map[string]error
+	folderPermissions map[string]*ResourcePermissions
+	libraryElements   map[string]*grafsdk.LibraryElement
+	dashboardUIDs     map[string]bool
+	dashboards        int
+	skipped           int
+}
+
+// begin restores the datasources, folders, library panels and alerting resources of a section and prepares the client for its dashboards.
+// Sections of backups taken with -all-orgs are restored into the org with the same name.
+func (i *importer) begin(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	i.client = i.root
+	i.org = ""
+	i.dashboards = 0
+	i.skipped = 0
+	i.dashboardUIDs = map[string]bool{}
+	if org != nil {
+		i.org = org.Name
+		targetOrg, err := i.root.GetOrgByName(ctx, org.Name)
 		if err != nil {
-			return fmt.Errorf("storage.NewClient: %w", err)
+			if !grafsdk.IsNotFound(err) {
+				return fmt.Errorf("GetOrgByName %s: %w", org.Name, err)
+			}
+			i.conf.logd("org %q does not exist, creating new one", org.Name)
+			if targetOrg, err = i.root.CreateOrg(ctx, org.Name); err != nil {
+				return fmt.Errorf("CreateOrg %s: %w", org.Name, err)
+			}
 		}
-		if backupReader, err = gcsClient.Bucket(bucketName).Object(objectName).NewReader(ctx); err != nil {
-			return fmt.Errorf("Bucket.NewReader: %w", err)
+		i.conf.logd("importing org %d:%q into org %d", org.ID, org.Name, targetOrg.ID)
+		i.client = i.root.withOrg(targetOrg.ID)
+	}
+	i.principals = newPrincipalResolver(i.client)
+
+	if err := i.importSection(ctx, grafanaBackup); err != nil {
+		if org != nil {
+			return fmt.Errorf("org %s: %w", org.Name, err)
 		}
-		defer backupReader.Close()
-	} else {
-		var err error
-		if backupReader, err = os.Open(c.Conf.Src); err != nil {
-			return fmt.Errorf("os.Open: %w", err)
+		return err
+	}
+	return nil
+}
+
+// importSection restores the datasources, folders, users, teams, folder permissions, library panels and alerting resources of a backup section into the client org.
+// When the filter selects dashboards, folders and library panels are only restored once a selected dashboard needs them.
+func (i *importer) importSection(ctx context.Context, grafanaBackup *GrafanaBackup) error {
+	client := i.client
+	i.conf.logd("found %d datasource(s) and %d folder(s)", len(grafanaBackup.Datasources), len(grafanaBackup.Folders))
+
+	selected := []*grafsdk.Datasource{}
+	for _, datasource := range grafanaBackup.Datasources {
+		if !i.filter.datasource(datasource.Name) {
+			i.conf.logd("skipping datasource %s:%s", datasource.UID, datasource.Name)
+			continue
+		}
+		if mapped := mappedDatasource(i.dsMap, datasource); mapped != "" {
+			i.conf.logd("skipping datasource %s:%s mapped to %s", datasource.UID, datasource.Name, mapped)
+			continue
 		}
+		selected = append(selected, datasource)
+		if err := i.importDatasource(ctx, datasource); err != nil {
+			if err := i.fail("datasource", datasource.UID, datasource.Name, err); err != nil {
+				return err
+			}
+		}
+	}
+	i.conf.logd("imported datasources")
+	if missing := i.secrets.missingSecrets(selected); len(missing) > 0 {
+		log.Printf("warning: %d datasource(s) had secrets when the backup was taken and none were supplied with -secrets, they are restored without them: %s", len(missing), strings.Join(missing, ", "))
 	}
 
-	gzipReader, err := gzip.NewReader(backupReader)
+	// dashboards and library panels reference the datasources by uid, the uids of the target datasources may differ
+	targetDatasources, err := client.ListDatasources(ctx)
 	if err != nil {
-		return fmt.Errorf("gzip.NewReader: %w", err)
+		return fmt.Errorf("ListDatasources: %w", err)
+	}
+	if i.remapper, err = newDatasourceRemapper(grafanaBackup.Datasources, targetDatasources, i.dsMap); err != nil {
+		return err
 	}
 
-	backupBy, err := io.ReadAll(gzipReader)
+	// backup folders are restored into the folders with the same uid, or the same title under the same parent
+	folders, err := client.fetchFolderTree(ctx)
 	if err != nil {
-		return fmt.Errorf("io.ReadAll: %w", err)
+		return err
+	}
+	i.backupFolders = grafanaBackup.Folders
+	i.targetFolders = folders
+	i.restoredFolders = map[string]*grafsdk.Folder{}
+	i.failedFolders = map[string]error{}
+	i.folderPermissions = map[string]*ResourcePermissions{}
+	for _, permissions := range grafanaBackup.FolderPermissions {
+		i.folderPermissions[permissions.UID] = permissions
 	}
-	if err := gzipReader.Close(); err != nil {
-		return fmt.Errorf("zr.Close: %w", err)
+	i.libraryElements = map[string]*grafsdk.LibraryElement{}
+	for _, element := range grafanaBackup.LibraryElements {
+		i.libraryElements[element.UID] = element
 	}
 
-	grafanaBackup := GrafanaBackup{}
-	if err := json.Unmarshal(backupBy, &grafanaBackup); err != nil {
-		return fmt.Errorf("json.Unmarshal: %w", err)
+	// users, teams and alerting resources can't be selected, only a full import restores them
+	if i.filter.selective() {
+		i.conf.logd("skipping users, teams and alerting resources of a selective import")
+		return nil
 	}
 
-	c.Conf.logd("found %d datasource(s), %d folder(s), and %d dashboard(s)", len(grafanaBackup.Datasources), len(grafanaBackup.Folders), len(grafanaBackup.Dashboards))
+	// the permissions reference the users and teams
+	if err := i.importUsersAndTeams(ctx, grafanaBackup.Users, grafanaBackup.Teams); err != nil {
+		return err
+	}
 
-	// upsert datasources
-	for _, datasource := range grafanaBackup.Datasources {
-		if existingDS, err := c.Conf.Client().GetDatasourceByName(ctx, datasource.Name); err == nil {
-			c.Conf.logd("datasource %d:%s:%s already exists, updating in place", datasource.ID, datasource.UID, datasource.Name)
-			datasource.ID = existingDS.ID
-			if err := c.Conf.Client().UpdateDatasource(ctx, datasource); err != nil {
-				return fmt.Errorf("UpdateDatasource %d %s: %w", datasource.ID, datasource.Name, err)
+	if !i.filter.selectsDashboards() {
+		for _, backupFolder := range grafanaBackup.Folders {
+			if i.filter.folderExcluded(backupFolder.Title) {
+				i.conf.logd("skipping folder %s:%q", backupFolder.UID, backupFolder.Title)
+				continue
+			}
+			if _, err := i.restoreFolder(ctx, backupFolder); err != nil {
+				if err := i.fail("folder", backupFolder.UID, backupFolder.Title, err); err != nil {
+					return err
+				}
 			}
-		} else {
-			c.Conf.logd("datasource %d:%s:%s does not exist, creating new one", datasource.ID, datasource.UID, datasource.Name)
-			datasource.ID = 0
-			if _, err := c.Conf.Client().CreateDatasource(ctx, datasource); err != nil {
-				return fmt.Errorf("CreateDatasource %d %s: %w", datasource.ID, datasource.Name, err)
+		}
+		// dashboards reference the library panels by uid, they are restored before the dashboards
+		for _, element := range grafanaBackup.LibraryElements {
+			if err := i.importLibraryElement(ctx, element); err != nil {
+				return err
 			}
 		}
+		if len(grafanaBackup.LibraryElements) > 0 {
+			i.conf.logd("imported %d library element(s)", len(grafanaBackup.LibraryElements))
+		}
 	}
-	c.Conf.logd("imported datasources")
 
-	// populate the map with the title of all backup folders
-	folderTitleIDMap := map[string]int64{}
-	for _, backupFolder := range grafanaBackup.Folders {
-		folderTitleIDMap[backupFolder.Title] = 0
+	if alerting := grafanaBackup.Alerting; alerting != nil {
+		folderUIDMap := map[string]string{}
+		ruleGroups := []*grafsdk.AlertRuleGroup{}
+		for _, ruleGroup := range alerting.RuleGroups {
+			backupFolder := findBackupFolder(i.backupFolders, ruleGroup.FolderUID, 0)
+			if backupFolder != nil && i.filter.folderExcluded(backupFolder.Title) {
+				i.conf.logd("skipping alert rule group %q of folder %q", ruleGroup.Title, backupFolder.Title)
+				continue
+			}
+			folder, err := i.restoreFolder(ctx, backupFolder)
+			if err != nil {
+				return err
+			}
+			if folder != nil {
+				folderUIDMap[ruleGroup.FolderUID] = folder.UID
+			}
+			ruleGroups = append(ruleGroups, ruleGroup)
+		}
+		alerting.RuleGroups = ruleGroups
+		if err := i.importAlerting(ctx, alerting, folderUIDMap); err != nil {
+			return err
+		}
 	}
-	// assign the existing folder id to the title map
-	folders, err := c.Conf.Client().ListFolders(ctx)
-	if err != nil {
+	return nil
+}
+
+// importDatasource upserts the datasource by name
+func (i *importer) importDatasource(ctx context.Context, backupDatasource *grafsdk.Datasource) error {
+	client := i.client
+	// the backup datasource keeps its uid, the remapper rewrites its references to the target uid
+	ds := *backupDatasource
+	datasource := &ds
+	hasSecrets := i.secrets.apply(datasource)
+	existingDS, err := client.GetDatasourceByName(ctx, datasource.Name)
+	switch {
+	case err == nil:
+		i.conf.logd("datasource %d:%s:%s already exists, updating in place", datasource.ID, datasource.UID, datasource.Name)
+		// dashboards of the target reference the datasource by its current uid
+		datasource.ID = existingDS.ID
+		datasource.UID = existingDS.UID
+		if err := client.UpdateDatasource(ctx, datasource); err != nil {
+			return fmt.Errorf("UpdateDatasource %d %s: %w", datasource.ID, datasource.Name, err)
+		}
+		i.journal.datasource(client, datasource.Name, existingDS)
+		if hasSecrets {
+			// grafana never returns the prior secrets
+			i.journal.notRolledBack("datasource secrets", datasource.Name)
+		}
+	case !grafsdk.IsNotFound(err):
+		return fmt.Errorf("GetDatasourceByName %s: %w", datasource.Name, err)
+	default:
+		i.conf.logd("datasource %d:%s:%s does not exist, creating new one", datasource.ID, datasource.UID, datasource.Name)
+		datasource.ID = 0
+		if _, err := client.CreateDatasource(ctx, datasource); err != nil {
+			return fmt.Errorf("CreateDatasource %d %s: %w", datasource.ID, datasource.Name, err)
+		}
+		i.journal.datasource(client, datasource.Name, nil)
+	}
+	return nil
+}
+
+// fail records the failure of a single resource and returns nil with -continue-on-error, the error otherwise
+func (i *importer) fail(kind string, uid string, name string, err error) error {
+	if i.failures == nil {
 		return err
 	}
+	log.Printf("warning: failed to import %s %s: %v", kind, name, err)
+	i.failures.add(&importFailure{Org: i.org, Kind: kind, UID: uid, Name: name, Error: err.Error()})
+	return nil
+}
+
+// findBackupFolder returns the backup folder with the uid or id, nil for the general folder
+func findBackupFolder(backupFolders []*grafsdk.Folder, uid string, id int64) *grafsdk.Folder {
+	if uid == "" && id == 0 {
+		return nil
+	}
+	for _, backupFolder := range backupFolders {
+		if (uid != "" && backupFolder.UID == uid) || (uid == "" && backupFolder.ID == id) {
+			return backupFolder
+		}
+	}
+	return nil
+}
+
+// dashboardBackupFolder returns the backup folder of a dashboard, dashboards of backups taken before the folder uids
+// were part of the dashboard meta are matched by folder title
+func dashboardBackupFolder(backupFolders []*grafsdk.Folder, meta *simplejson.Json) *grafsdk.Folder {
+	if backupFolder := findBackupFolder(backupFolders, meta.Get("folderUid").MustString(), meta.Get("folderId").MustInt64()); backupFolder != nil {
+		return backupFolder
+	}
+	title := meta.Get("folderTitle").MustString()
+	for _, backupFolder := range backupFolders {
+		if backupFolder.Title == title {
+			return backupFolder
+		}
+	}
+	return nil
+}
+
+// findFolder returns the folder with the uid, or with the title under the parent uid
+func findFolder(folders []*grafsdk.Folder, uid string, parentUID string, title string) *grafsdk.Folder {
 	for _, folder := range folders {
-		folderTitleIDMap[folder.Title] = folder.ID
+		if folder.UID == uid {
+			return folder
+		}
 	}
-	// create folders that do not exist
-	for title, id := range folderTitleIDMap {
-		if id != 0 {
-			continue
+	for _, folder := range folders {
+		if folder.Title == title && folder.ParentUID == parentUID {
+			return folder
 		}
-		c.Conf.logd("folder %s does not exist, creating new one", title)
-		folder, err := c.Conf.Client().CreateFolder(ctx, title)
+	}
+	return nil
+}
+
+// restoreFolder returns the target folder of a backup folder, nil for the general folder. On first use the parent
+// folders are restored, the folder is created with the backup uid when missing and its permissions are restored.
+func (i *importer) restoreFolder(ctx context.Context, backupFolder *grafsdk.Folder) (*grafsdk.Folder, error) {
+	if backupFolder == nil {
+		return nil, nil
+	}
+	if folder, ok := i.restoredFolders[backupFolder.UID]; ok {
+		return folder, nil
+	}
+	if err, ok := i.failedFolders[backupFolder.UID]; ok {
+		return nil, err
+	}
+	folder, err := i.createFolder(ctx, backupFolder)
+	if err != nil {
+		i.failedFolders[backupFolder.UID] = err
+		return nil, err
+	}
+	i.restoredFolders[backupFolder.UID] = folder
+
+	if permissions, ok := i.folderPermissions[backupFolder.UID]; ok {
+		if err := i.importFolderPermissions(ctx, permissions, folder.UID); err != nil {
+			return nil, err
+		}
+	}
+	return folder, nil
+}
+
+// createFolder returns the existing target folder of a backup folder or creates it under its restored parent
+func (i *importer) createFolder(ctx context.Context, backupFolder *grafsdk.Folder) (*grafsdk.Folder, error) {
+	var parentUID string
+	if backupFolder.ParentUID != "" {
+		parent, err := i.restoreFolder(ctx, findBackupFolder(i.backupFolders, backupFolder.ParentUID, 0))
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if parent != nil {
+			parentUID = parent.UID
 		}
-		folderTitleIDMap[title] = folder.ID
+	}
+	if folder := findFolder(i.targetFolders, backupFolder.UID, parentUID, backupFolder.Title); folder != nil {
+		return folder, nil
 	}
 
-	// create a map with the backup folder IDs and the new folder IDs
-	folderBackupIDMap := map[int64]int64{}
-	for _, backupFolder := range grafanaBackup.Folders {
-		folderBackupIDMap[backupFolder.ID] = folderTitleIDMap[backupFolder.Title]
+	i.conf.logd("folder %s:%s does not exist, creating new one", backupFolder.UID, backupFolder.Title)
+	folder, err := i.client.CreateFolder(ctx, &grafsdk.Folder{UID: backupFolder.UID, Title: backupFolder.Title, ParentUID: parentUID})
+	if err != nil {
+		return nil, fmt.Errorf("CreateFolder %s %s: %w", backupFolder.UID, backupFolder.Title, err)
 	}
+	i.journal.folder(i.client, folder)
+	i.targetFolders = append(i.targetFolders, folder)
+	return folder, nil
+}
 
-	// dashboards
-	for _, dashboardFull := range grafanaBackup.Dashboards {
-		dashboard := dashboardFull.Dashboard
-		dashboardMeta := dashboardFull.Meta
-		dashboard.Del("id") // delete references to numeric id
-		uid := dashboard.Get("uid").MustString()
-		title := dashboard.Get("title").MustString()
-		folderTitle := dashboardMeta.Get("folderTitle").MustString()
-		folderID := folderTitleIDMap[folderTitle]
-		c.Conf.logd("importing dashboard %s:%q from folder %d:%q", uid, title, folderID, folderTitle)
-		dashboard.Set("folderId", folderID)
+// importLibraryElement upserts the library element by uid into the target folder of its backup folder
+func (i *importer) importLibraryElement(ctx context.Context, element *grafsdk.LibraryElement) error {
+	client := i.client
+	delete(i.libraryElements, element.UID)
+	element.ID = 0
+	element.OrgID = 0
+	element.Meta = nil
+	if element.Model != nil {
+		i.remapper.rewrite(element.Model)
+	}
+	folder, err := i.restoreFolder(ctx, findBackupFolder(i.backupFolders, element.FolderUID, element.FolderID))
+	if err != nil {
+		return err
+	}
+	element.FolderUID = ""
+	element.FolderID = 0
+	if folder != nil {
+		element.FolderUID = folder.UID
+		element.FolderID = folder.ID
+	}
 
-		// dashboard list panels have a reference to the numeric folder id
-		// we track the new folder id's in a map so we can update the panel references
-		for _, p := range dashboard.Get("panels").MustArray() {
-			panel := simplejson.NewFromAny(p)
-			if panel.Get("type").MustString() != "dashlist" {
-				continue
+	existing, err := client.GetLibraryElementByUID(ctx, element.UID)
+	switch {
+	case err == nil:
+		i.conf.logd("library element %s:%q already exists, updating in place", element.UID, element.Name)
+		element.Version = existing.Version
+		if _, err := client.UpdateLibraryElement(ctx, element); err != nil {
+			return fmt.Errorf("UpdateLibraryElement %s %s: %w", element.UID, element.Name, err)
+		}
+		i.journal.libraryElement(client, element.UID, element.Name, existing)
+	case !grafsdk.IsNotFound(err):
+		return fmt.Errorf("GetLibraryElementByUID %s: %w", element.UID, err)
+	default:
+		i.conf.logd("library element %s:%q does not exist, creating new one", element.UID, element.Name)
+		if _, err := client.CreateLibraryElement(ctx, element); err != nil {
+			return fmt.Errorf("CreateLibraryElement %s %s: %w", element.UID, element.Name, err)
+		}
+		i.journal.libraryElement(client, element.UID, element.Name, nil)
+	}
+	return nil
+}
+
+// libraryPanelUIDs returns the uids of the library panels referenced by the dashboard panels and collapsed rows
+func libraryPanelUIDs(panels []interface{}) []string {
+	uids := []string{}
+	for _, p := range panels {
+		panel := simplejson.NewFromAny(p)
+		if uid := panel.GetPath("libraryPanel", "uid").MustString(); uid != "" {
+			uids = append(uids, uid)
+		}
+		uids = append(uids, libraryPanelUIDs(panel.Get("panels").MustArray())...)
+	}
+	return uids
+}
+
+// dashboard restores a single selected dashboard of the current section
+func (i *importer) dashboard(ctx context.Context, dashboardFull *grafsdk.DashboardWithMeta) error {
+	dashboard := dashboardFull.Dashboard
+	dashboardMeta := dashboardFull.Meta
+	dashboard.Del("id") // delete references to numeric id
+	uid := dashboard.Get("uid").MustString()
+	title := dashboard.Get("title").MustString()
+	folderTitle := dashboardMeta.Get("folderTitle").MustString()
+	if !i.filter.dashboard(uid, folderTitle, dashboard.Get("tags").MustStringArray()) {
+		i.conf.logd("skipping dashboard %s:%q from folder %q", uid, title, folderTitle)
+		i.skipped++
+		return nil
+	}
+	if err := i.importDashboard(ctx, dashboard, uid, title, dashboardBackupFolder(i.backupFolders, dashboardMeta)); err != nil {
+		return i.fail("dashboard", uid, title, err)
+	}
+	i.dashboardUIDs[uid] = true
+	i.dashboards++
+	return nil
+}
+
+// importDashboard saves the dashboard into the target folder of its backup folder, the folder and the library panels
+// of the dashboard are restored first
+func (i *importer) importDashboard(ctx context.Context, dashboard *simplejson.Json, uid string, title string, backupFolder *grafsdk.Folder) error {
+	folder, err := i.restoreFolder(ctx, backupFolder)
+	if err != nil {
+		return err
+	}
+	var folderID int64
+	folderUID := ""
+	if folder != nil {
+		folderID = folder.ID
+		folderUID = folder.UID
+	}
+	i.conf.logd("importing dashboard %s:%q into folder %d:%q", uid, title, folderID, folderUID)
+	if rewritten := i.remapper.rewrite(dashboard); rewritten > 0 {
+		i.conf.logd("remapped %d datasource reference(s) of dashboard %s", rewritten, uid)
+	}
+	dashboard.Set("folderId", folderID)
+
+	// library panels not restored yet are restored with the first dashboard referencing them
+	for _, libraryUID := range libraryPanelUIDs(dashboard.Get("panels").MustArray()) {
+		if element, ok := i.libraryElements[libraryUID]; ok {
+			if err := i.importLibraryElement(ctx, element); err != nil {
+				return err
 			}
-			oldFolderID := panel.Get("folderId").MustInt64()
-			newFolderID := folderBackupIDMap[panel.Get("folderId").MustInt64()]
-			c.Conf.logd("updating dash list folder ID from %d do %d", oldFolderID, newFolderID)
-			panel.Set("folderId", newFolderID)
 		}
+	}
+
+	remapDashlistFolders(dashboard, i.backupFolders, func(backupFolder *grafsdk.Folder) *grafsdk.Folder {
+		if folder, ok := i.restoredFolders[backupFolder.UID]; ok {
+			return folder
+		}
+		return findFolder(i.targetFolders, backupFolder.UID, backupFolder.ParentUID, backupFolder.Title)
+	})
 
-		if err := c.Conf.Client().SaveDashboard(ctx, &grafsdk.DashboardSavePayload{
-			Dashboard: dashboard,
-			Overwrite: true,
-			FolderID:  folderID,
-		}); err != nil {
-			return fmt.Errorf("SaveDashboard: %w", err)
+	// the journal keeps the current dashboard to restore it when the import fails
+	var prior *grafsdk.DashboardWithMeta
+	if i.journal != nil {
+		if prior, err = i.client.GetDashboardByUID(ctx, uid); err != nil && !grafsdk.IsNotFound(err) {
+			return fmt.Errorf("GetDashboardByUID %s: %w", uid, err)
 		}
 	}
+	if err := i.client.SaveDashboard(ctx, &grafsdk.DashboardSavePayload{
+		Dashboard: dashboard,
+		Overwrite: true,
+		FolderID:  folderID,
+		FolderUID: folderUID,
+	}); err != nil {
+		return fmt.Errorf("SaveDashboard %s: %w", uid, err)
+	}
+	i.journal.dashboard(i.client, uid, prior)
+	return nil
+}
 
+// remapDashlistFolders rewrites the numeric folder id of the dashboard list panels, the ids differ between grafana
+// instances. targetFolder returns the target folder of a backup folder, nil when it does not exist.
+func remapDashlistFolders(dashboard *simplejson.Json, backupFolders []*grafsdk.Folder, targetFolder func(backupFolder *grafsdk.Folder) *grafsdk.Folder) {
+	for _, p := range dashboard.Get("panels").MustArray() {
+		panel := simplejson.NewFromAny(p)
+		if panel.Get("type").MustString() != "dashlist" {
+			continue
+		}
+		var folderID int64
+		if backupFolder := findBackupFolder(backupFolders, "", panel.Get("folderId").MustInt64()); backupFolder != nil {
+			if folder := targetFolder(backupFolder); folder != nil {
+				folderID = folder.ID
+			}
+		}
+		panel.Set("folderId", folderID)
+	}
+}
+
+// end restores the permissions of the restored dashboards of the section once they exist
+func (i *importer) end(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	if i.skipped > 0 {
+		i.conf.logd("imported %d dashboard(s), skipped %d dashboard(s)", i.dashboards, i.skipped)
+	} else {
+		i.conf.logd("imported %d dashboard(s)", i.dashboards)
+	}
+	dashboardPermissions := []*ResourcePermissions{}
+	for _, permissions := range grafanaBackup.DashboardPermissions {
+		if i.dashboardUIDs[permissions.UID] {
+			dashboardPermissions = append(dashboardPermissions, permissions)
+		}
+	}
+	if err := i.importDashboardPermissions(ctx, dashboardPermissions); err != nil {
+		if org != nil {
+			return fmt.Errorf("org %s: %w", org.Name, err)
+		}
+		return err
+	}
 	return nil
 }
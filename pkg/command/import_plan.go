@@ -0,0 +1,344 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/olekukonko/tablewriter"
+)
+
+// ExitChangesPending is the exit code of import -dry-run when the import would change the target grafana
+const ExitChangesPending = 2
+
+// plan actions of import -dry-run
+const (
+	planCreate    = "create"
+	planUpdate    = "update"
+	planUnchanged = "unchanged"
+	planConflict  = "conflict"
+	// planNotCompared resources are imported without being compared with the target, they count as pending
+	planNotCompared = "not compared"
+)
+
+// planChange is a planned change of a single resource, versions are empty for resources without one
+type planChange struct {
+	kind           string
+	name           string
+	action         string
+	currentVersion string
+	backupVersion  string
+}
+
+// importPlanner is a backupVisitor comparing the selected resources of every section of a backup with the target
+// grafana, it never changes the target
+type importPlanner struct {
+	root   *Client
+	filter *importFilter
+	dsMap  map[string]string
+	w      io.Writer
+
+	// client, folders and changes of the section being planned
+	client         *Client
+	remapper       *datasourceRemapper
+	backupFolders  []*grafsdk.Folder
+	targetFolders  []*grafsdk.Folder
+	plannedFolders map[string]string
+	dashboardUIDs  map[string]bool
+	libraryPanels  map[string]bool
+	changes        []*planChange
+	counts         map[string]int
+}
+
+func (p *importPlanner) add(change *planChange) {
+	p.changes = append(p.changes, change)
+	if p.counts == nil {
+		p.counts = map[string]int{}
+	}
+	p.counts[change.action]++
+}
+
+// begin plans the datasources and folders of a section, orgs missing from the target are planned as created with
+// every resource of the section
+func (p *importPlanner) begin(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	p.client = p.root
+	p.changes = nil
+	p.backupFolders = grafanaBackup.Folders
+	p.targetFolders = nil
+	p.plannedFolders = map[string]string{}
+	p.dashboardUIDs = map[string]bool{}
+	p.libraryPanels = map[string]bool{}
+	if org != nil {
+		targetOrg, err := p.root.GetOrgByName(ctx, org.Name)
+		switch {
+		case err == nil:
+			p.client = p.root.withOrg(targetOrg.ID)
+		case !grafsdk.IsNotFound(err):
+			return fmt.Errorf("GetOrgByName %s: %w", org.Name, err)
+		default:
+			p.add(&planChange{kind: "org", name: org.Name, action: planCreate})
+			p.client = nil
+		}
+	}
+
+	for _, datasource := range grafanaBackup.Datasources {
+		if !p.filter.datasource(datasource.Name) || mappedDatasource(p.dsMap, datasource) != "" {
+			continue
+		}
+		if err := p.planDatasource(ctx, datasource); err != nil {
+			return err
+		}
+	}
+
+	var targetDatasources []*grafsdk.Datasource
+	if p.client != nil {
+		folders, err := p.client.fetchFolderTree(ctx)
+		if err != nil {
+			return err
+		}
+		p.targetFolders = folders
+		if targetDatasources, err = p.client.ListDatasources(ctx); err != nil {
+			return fmt.Errorf("ListDatasources: %w", err)
+		}
+	}
+	// datasources missing from the target are created with their backup uid
+	remapper, err := newDatasourceRemapper(grafanaBackup.Datasources, targetDatasources, p.dsMap)
+	if err != nil {
+		return err
+	}
+	p.remapper = remapper
+	if !p.filter.selective() && !p.filter.selectsDashboards() {
+		for _, backupFolder := range grafanaBackup.Folders {
+			if !p.filter.folderExcluded(backupFolder.Title) {
+				p.planFolder(backupFolder)
+			}
+		}
+	}
+	// the folders of the alert rule groups are restored with them
+	if alerting := grafanaBackup.Alerting; alerting != nil && !p.filter.selective() {
+		for _, ruleGroup := range alerting.RuleGroups {
+			backupFolder := findBackupFolder(p.backupFolders, ruleGroup.FolderUID, 0)
+			if backupFolder != nil && !p.filter.folderExcluded(backupFolder.Title) {
+				p.planFolder(backupFolder)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *importPlanner) planDatasource(ctx context.Context, datasource *grafsdk.Datasource) error {
+	change := planChange{kind: "datasource", name: datasource.Name, action: planCreate, backupVersion: strconv.Itoa(datasource.Version)}
+	defer p.add(&change)
+	if p.client == nil {
+		return nil
+	}
+	existing, err := p.client.GetDatasourceByName(ctx, datasource.Name)
+	if grafsdk.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("GetDatasourceByName %s: %w", datasource.Name, err)
+	}
+	change.currentVersion = strconv.Itoa(existing.Version)
+	switch {
+	case existing.Type != datasource.Type:
+		change.action = planConflict
+	case sameDatasource(existing, datasource):
+		change.action = planUnchanged
+	default:
+		change.action = planUpdate
+	}
+	return nil
+}
+
+// sameDatasource compares the settings of two datasources, ids and versions differ between instances and the import
+// keeps the uid of existing datasources
+func sameDatasource(a *grafsdk.Datasource, b *grafsdk.Datasource) bool {
+	normalize := func(datasource grafsdk.Datasource) string {
+		datasource.ID = 0
+		datasource.UID = ""
+		datasource.OrgID = 0
+		datasource.Version = 0
+		by, _ := json.Marshal(datasource)
+		return string(by)
+	}
+	return normalize(*a) == normalize(*b)
+}
+
+// planFolder plans a backup folder and its parents once and returns the uid of its target folder, folders missing
+// from the target are created with the backup uid
+func (p *importPlanner) planFolder(backupFolder *grafsdk.Folder) string {
+	if backupFolder == nil {
+		return ""
+	}
+	if uid, ok := p.plannedFolders[backupFolder.UID]; ok {
+		return uid
+	}
+	var parentUID string
+	if backupFolder.ParentUID != "" {
+		parentUID = p.planFolder(findBackupFolder(p.backupFolders, backupFolder.ParentUID, 0))
+	}
+	action, uid := planCreate, backupFolder.UID
+	if folder := findFolder(p.targetFolders, backupFolder.UID, parentUID, backupFolder.Title); folder != nil {
+		action, uid = planUnchanged, folder.UID
+	}
+	p.plannedFolders[backupFolder.UID] = uid
+	p.add(&planChange{kind: "folder", name: backupFolder.Title, action: action})
+	return uid
+}
+
+func (p *importPlanner) dashboard(ctx context.Context, dashboardFull *grafsdk.DashboardWithMeta) error {
+	dashboard := dashboardFull.Dashboard
+	uid := dashboard.Get("uid").MustString()
+	folderTitle := dashboardFull.Meta.Get("folderTitle").MustString()
+	if !p.filter.dashboard(uid, folderTitle, dashboard.Get("tags").MustStringArray()) {
+		return nil
+	}
+	folderUID := p.planFolder(dashboardBackupFolder(p.backupFolders, dashboardFull.Meta))
+	p.remapper.rewrite(dashboard)
+	remapDashlistFolders(dashboard, p.backupFolders, func(backupFolder *grafsdk.Folder) *grafsdk.Folder {
+		if uid, ok := p.plannedFolders[backupFolder.UID]; ok {
+			// folders planned to be created have no id yet
+			for _, folder := range p.targetFolders {
+				if folder.UID == uid {
+					return folder
+				}
+			}
+			return nil
+		}
+		return findFolder(p.targetFolders, backupFolder.UID, backupFolder.ParentUID, backupFolder.Title)
+	})
+	p.dashboardUIDs[uid] = true
+	for _, libraryUID := range libraryPanelUIDs(dashboard.Get("panels").MustArray()) {
+		p.libraryPanels[libraryUID] = true
+	}
+
+	version := dashboard.Get("version").MustInt64()
+	change := planChange{kind: "dashboard", name: uid, action: planCreate, backupVersion: strconv.FormatInt(version, 10)}
+	defer p.add(&change)
+	if p.client == nil {
+		return nil
+	}
+	existing, err := p.client.GetDashboardByUID(ctx, uid)
+	if grafsdk.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("GetDashboardByUID %s: %w", uid, err)
+	}
+	existingVersion := existing.Dashboard.Get("version").MustInt64()
+	change.currentVersion = strconv.FormatInt(existingVersion, 10)
+	// grafana raises the version on every save, an imported dashboard has a newer version with the same content
+	switch {
+	case existing.Meta.Get("folderUid").MustString() == folderUID && sameDashboard(existing.Dashboard.Interface(), dashboard.Interface()):
+		change.action = planUnchanged
+	case existingVersion > version:
+		// the dashboard changed after the backup was taken, importing overwrites the newer version
+		change.action = planConflict
+	default:
+		change.action = planUpdate
+	}
+	return nil
+}
+
+// sameDashboard compares the models of two dashboards ignoring their numeric ids and version
+func sameDashboard(a interface{}, b interface{}) bool {
+	normalize := func(dashboard interface{}) string {
+		model := map[string]interface{}{}
+		if m, ok := dashboard.(map[string]interface{}); ok {
+			for key, value := range m {
+				model[key] = value
+			}
+		}
+		delete(model, "id")
+		delete(model, "folderId")
+		delete(model, "version")
+		by, _ := json.Marshal(model)
+		return string(by)
+	}
+	return normalize(a) == normalize(b)
+}
+
+// end plans the resources of the section the import restores without comparing them and prints the plan
+func (p *importPlanner) end(ctx context.Context, org *grafsdk.Org, grafanaBackup *GrafanaBackup) error {
+	p.planNotCompared(grafanaBackup)
+	if org != nil {
+		fmt.Fprintf(p.w, "Org %d: %s\n", org.ID, org.Name)
+	}
+	table := tablewriter.NewWriter(p.w)
+	table.SetHeader([]string{"Kind", "Name", "Action", "Current Version", "Backup Version"})
+	for _, change := range p.changes {
+		table.Append([]string{change.kind, change.name, change.action, change.currentVersion, change.backupVersion})
+	}
+	table.Render()
+	fmt.Fprintln(p.w)
+	return nil
+}
+
+// planNotCompared adds a change per kind of resource the import restores without the plan comparing them with the
+// target, eg; library panels, permissions and alerting resources. They count as pending changes.
+func (p *importPlanner) planNotCompared(grafanaBackup *GrafanaBackup) {
+	notCompared := func(kind string, count int) {
+		if count > 0 {
+			p.add(&planChange{kind: kind, name: fmt.Sprintf("%d item(s)", count), action: planNotCompared})
+		}
+	}
+	libraryElements := 0
+	for _, element := range grafanaBackup.LibraryElements {
+		if !p.filter.selectsDashboards() || p.libraryPanels[element.UID] {
+			libraryElements++
+		}
+	}
+	notCompared("library panels", libraryElements)
+	folderPermissions := 0
+	for _, permissions := range grafanaBackup.FolderPermissions {
+		if _, ok := p.plannedFolders[permissions.UID]; ok {
+			folderPermissions++
+		}
+	}
+	notCompared("folder permissions", folderPermissions)
+	dashboardPermissions := 0
+	for _, permissions := range grafanaBackup.DashboardPermissions {
+		if p.dashboardUIDs[permissions.UID] {
+			dashboardPermissions++
+		}
+	}
+	notCompared("dashboard permissions", dashboardPermissions)
+
+	// users, teams and alerting resources are only restored without -include
+	if p.filter.selective() {
+		return
+	}
+	notCompared("users", len(grafanaBackup.Users))
+	notCompared("teams", len(grafanaBackup.Teams))
+	if alerting := grafanaBackup.Alerting; alerting != nil {
+		ruleGroups := 0
+		for _, ruleGroup := range alerting.RuleGroups {
+			backupFolder := findBackupFolder(p.backupFolders, ruleGroup.FolderUID, 0)
+			if backupFolder == nil || !p.filter.folderExcluded(backupFolder.Title) {
+				ruleGroups++
+			}
+		}
+		notCompared("alert rule groups", ruleGroups)
+		notCompared("contact points", len(alerting.ContactPoints))
+		notCompared("mute timings", len(alerting.MuteTimings))
+		notCompared("notification templates", len(alerting.Templates))
+		if alerting.Policies != nil {
+			notCompared("notification policies", 1)
+		}
+	}
+}
+
+// pending returns the number of planned changes, conflicts are changes overwriting the target and the resources that
+// were not compared may change the target
+func (p *importPlanner) pending() int {
+	return p.counts[planCreate] + p.counts[planUpdate] + p.counts[planConflict] + p.counts[planNotCompared]
+}
+
+// summary prints the number of planned changes of every section
+func (p *importPlanner) summary() {
+	fmt.Fprintf(p.w, "Plan: %d to create, %d to update, %d unchanged, %d conflict(s), %d not compared\n", p.counts[planCreate], p.counts[planUpdate], p.counts[planUnchanged], p.counts[planConflict], p.counts[planNotCompared])
+}
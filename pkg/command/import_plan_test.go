@@ -0,0 +1,139 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportPlanner(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.addDatasource("prometheus", "prometheus")
+	sourceOrg.addDatasource("postgres", "postgres")
+	mainFolder := sourceOrg.addFolder("main-folder", "Main Folder")
+	newFolder := sourceOrg.addFolder("new-folder", "New Folder")
+	sourceOrg.addDashboard("same-dash", "Same", mainFolder)
+	sourceOrg.addDashboard("changed-dash", "Changed", mainFolder)
+	sourceOrg.addDashboard("newer-dash", "Newer", nil)
+	sourceOrg.addDashboard("same-newer-dash", "Same Newer", nil)
+	sourceOrg.addDashboard("new-dash", "New", newFolder)
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.addDatasource("prometheus", "prometheus")
+	targetMainFolder := targetOrg.addFolder("other-uid", "Main Folder")
+	targetOrg.addDashboard("same-dash", "Same", targetMainFolder)
+	targetOrg.addDashboard("changed-dash", "Changed in the target", targetMainFolder)
+	targetOrg.addDashboard("newer-dash", "Newer in the target", nil).Dashboard.Set("version", 5)
+	targetOrg.addDashboard("same-newer-dash", "Same Newer", nil).Dashboard.Set("version", 4)
+
+	var buf bytes.Buffer
+	filter, err := parseImportFilter(nil, nil)
+	assert.NoError(t, err)
+	planner := importPlanner{root: target.client(), filter: filter, w: &buf}
+	conf := &RootConfig{}
+	_, err = conf.readBackup(context.Background(), src, &planner)
+	assert.NoError(t, err)
+	planner.summary()
+	out := buf.String()
+
+	assert.Regexp(t, `datasource\s+\|\s+prometheus\s+\|\s+unchanged\s+\|\s+0\s+\|\s+0`, out)
+	assert.Regexp(t, `datasource\s+\|\s+postgres\s+\|\s+create\s+\|\s+\|\s+0`, out)
+	assert.Regexp(t, `folder\s+\|\s+Main Folder\s+\|\s+unchanged`, out)
+	assert.Regexp(t, `folder\s+\|\s+New Folder\s+\|\s+create`, out)
+	assert.Regexp(t, `dashboard\s+\|\s+same-dash\s+\|\s+unchanged\s+\|\s+1\s+\|\s+1`, out)
+	assert.Regexp(t, `dashboard\s+\|\s+changed-dash\s+\|\s+update\s+\|\s+1\s+\|\s+1`, out)
+	assert.Regexp(t, `dashboard\s+\|\s+newer-dash\s+\|\s+conflict\s+\|\s+5\s+\|\s+1`, out)
+	// a newer version with the same content, eg; a dashboard the import saved, is unchanged
+	assert.Regexp(t, `dashboard\s+\|\s+same-newer-dash\s+\|\s+unchanged\s+\|\s+4\s+\|\s+1`, out)
+	assert.Regexp(t, `dashboard\s+\|\s+new-dash\s+\|\s+create\s+\|\s+\|\s+1`, out)
+	assert.Contains(t, out, "Plan: 3 to create, 1 to update, 4 unchanged, 1 conflict(s), 0 not compared")
+	assert.Equal(t, 5, planner.pending())
+
+	// the dry run signals the pending changes with its exit code and leaves the target untouched
+	err = runImport(t, target, "-src", src, "-dry-run")
+	exitErr := &ExitError{}
+	assert.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, ExitChangesPending, exitErr.Code)
+	assert.Len(t, targetOrg.datasources, 1)
+	assert.Len(t, targetOrg.folders, 1)
+	assert.Equal(t, "Changed in the target", targetOrg.dashboard("changed-dash").Dashboard.Get("title").MustString())
+
+	// nothing is pending once the backup is imported, even over dashboards whose version the import raised
+	assert.NoError(t, runImport(t, target, "-src", src))
+	assert.Equal(t, int64(2), targetOrg.dashboard("same-dash").Dashboard.Get("version").MustInt64())
+	assert.NoError(t, runImport(t, target, "-src", src, "-dry-run"))
+	target = newFakeGrafana(t)
+	assert.NoError(t, runImport(t, target, "-src", src))
+	assert.NoError(t, runImport(t, target, "-src", src, "-dry-run"))
+}
+
+func TestImportPlannerNotCompared(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.libraryElements = []*grafsdk.LibraryElement{}
+	sourceOrg.addLibraryPanel("cpu-panel", "CPU", nil)
+	sourceOrg.enableAlerting()
+	sourceOrg.addAlertRuleGroup(sourceOrg.addFolder("alerts-folder", "Alerts"), "cpu", "rule-1")
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	target.orgs[1].libraryElements = []*grafsdk.LibraryElement{}
+	target.orgs[1].enableAlerting()
+	assert.NoError(t, runImport(t, target, "-src", src))
+
+	// the library panels and alerting resources are imported without being compared, they are always pending
+	var buf bytes.Buffer
+	filter, err := parseImportFilter(nil, nil)
+	assert.NoError(t, err)
+	planner := importPlanner{root: target.client(), filter: filter, w: &buf}
+	_, err = (&RootConfig{}).readBackup(context.Background(), src, &planner)
+	assert.NoError(t, err)
+	planner.summary()
+	out := buf.String()
+	assert.Regexp(t, `folder\s+\|\s+Alerts\s+\|\s+unchanged`, out)
+	assert.Regexp(t, `library panels\s+\|\s+1 item\(s\)\s+\|\s+not compared`, out)
+	assert.Regexp(t, `alert rule groups\s+\|\s+1 item\(s\)\s+\|\s+not compared`, out)
+	assert.Regexp(t, `notification policies\s+\|\s+1 item\(s\)\s+\|\s+not compared`, out)
+	assert.NotContains(t, out, "contact points")
+	assert.Contains(t, out, "Plan: 0 to create, 0 to update, 1 unchanged, 0 conflict(s), 3 not compared")
+	assert.Equal(t, 3, planner.pending())
+
+	// a selective import of a dashboard without library panels leaves them and the alerting resources out
+	buf.Reset()
+	filter, err = parseImportFilter([]string{"dashboard:none"}, nil)
+	assert.NoError(t, err)
+	planner = importPlanner{root: target.client(), filter: filter, w: &buf}
+	_, err = (&RootConfig{}).readBackup(context.Background(), src, &planner)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, planner.pending())
+}
+
+func TestImportPlannerDashlist(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	folder := sourceOrg.addFolder("team-folder", "Team")
+	sourceOrg.addDashboard("home-dash", "Home", nil).Dashboard.Set("panels", []interface{}{
+		map[string]interface{}{"id": 1, "type": "dashlist", "folderId": folder.ID},
+	})
+	src := backupFile(t, source, BackupOptions{})
+
+	// the target folder gets another numeric id
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.addFolder("other-folder", "Other")
+	assert.NoError(t, runImport(t, target, "-src", src))
+	targetFolder := targetOrg.folders[1]
+	assert.NotEqual(t, folder.ID, targetFolder.ID)
+	panel := targetOrg.dashboard("home-dash").Dashboard.Get("panels").GetIndex(0)
+	assert.Equal(t, targetFolder.ID, panel.Get("folderId").MustInt64())
+
+	// the dry run remaps the dashboard list folder like the import, nothing is pending
+	assert.NoError(t, runImport(t, target, "-src", src, "-dry-run"))
+}
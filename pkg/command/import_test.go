@@ -0,0 +1,207 @@
+package command
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/stretchr/testify/assert"
+)
+
+// runImport runs `grafctl import` against the fake grafana with the given import flags
+func runImport(t *testing.T, grafana *fakeGrafana, args ...string) error {
+	rootCmd := NewRootCmd()
+	rootArgs := append([]string{"-config", filepath.Join(t.TempDir(), "config.yaml"), "-url", grafana.URL, "-key", "test-key", "-retries", "0", "import"}, args...)
+	if err := rootCmd.Parse(rootArgs); err != nil {
+		return err
+	}
+	return rootCmd.Run(context.Background())
+}
+
+// backupFile backs up the fake grafana to a local archive and returns its path
+func backupFile(t *testing.T, grafana *fakeGrafana, opts BackupOptions) string {
+	dir := t.TempDir()
+	opts.Store = NewLocalBackupStore(dir)
+	assert.NoError(t, grafana.client().BackupGrafana(context.Background(), opts))
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json.gz*"))
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	return matches[0]
+}
+
+func TestImportAllOrgs(t *testing.T) {
+	source := newFakeGrafana(t)
+	mainOrg := source.orgs[1]
+	mainOrg.addDatasource("prometheus", "prometheus")
+	mainOrg.addDashboard("main-dash", "Main", mainOrg.addFolder("main-folder", "Main Folder"))
+	teamOrg := source.addOrg(2, "Team")
+	teamOrg.addDatasource("postgres", "postgres")
+	teamOrg.addDashboard("team-dash", "Team", teamOrg.addFolder("team-folder", "Team Folder"))
+	src := backupFile(t, source, BackupOptions{AllOrgs: true})
+
+	target := newFakeGrafana(t)
+	assert.NoError(t, runImport(t, target, "-src", src))
+
+	assert.Len(t, target.orgs, 2)
+	assert.Equal(t, "Team", target.orgs[2].org.Name)
+	assert.Equal(t, "prometheus", target.orgs[1].datasources[0].Name)
+	assert.NotNil(t, target.orgs[1].dashboard("main-dash"))
+	assert.Nil(t, target.orgs[1].dashboard("team-dash"))
+	assert.Equal(t, "postgres", target.orgs[2].datasources[0].Name)
+	assert.Equal(t, "Team Folder", target.orgs[2].folders[0].Title)
+	teamDash := target.orgs[2].dashboard("team-dash")
+	assert.NotNil(t, teamDash)
+	assert.Equal(t, "Team Folder", teamDash.Meta.Get("folderTitle").MustString())
+}
+
+func TestImportLibraryPanels(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	folder := sourceOrg.addFolder("panels-folder", "Panels")
+	sourceOrg.addLibraryPanel("cpu-panel", "CPU", folder)
+	sourceOrg.addLibraryPanel("general-panel", "Memory", nil)
+	dashboard := sourceOrg.addDashboard("main-dash", "Main", nil)
+	dashboard.Dashboard.Set("panels", []interface{}{
+		map[string]interface{}{"id": 1, "libraryPanel": map[string]interface{}{"uid": "cpu-panel", "name": "CPU"}},
+	})
+	src := backupFile(t, source, BackupOptions{})
+
+	// the folder exists in the target with another uid and the general panel with another version
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.addFolder("other-uid", "Panels")
+	targetOrg.addLibraryPanel("general-panel", "Old", nil).Version = 7
+	assert.NoError(t, runImport(t, target, "-src", src))
+
+	assert.Len(t, targetOrg.libraryElements, 2)
+	cpuPanel := targetOrg.libraryElement("cpu-panel")
+	assert.NotNil(t, cpuPanel)
+	assert.Equal(t, "other-uid", cpuPanel.FolderUID)
+	assert.Equal(t, targetOrg.folders[0].ID, cpuPanel.FolderID)
+	assert.Equal(t, "CPU", cpuPanel.Model.Get("title").MustString())
+	generalPanel := targetOrg.libraryElement("general-panel")
+	assert.Equal(t, "Memory", generalPanel.Name)
+	assert.Equal(t, int64(8), generalPanel.Version)
+	assert.Equal(t, "", generalPanel.FolderUID)
+
+	libraryPanel := targetOrg.dashboard("main-dash").Dashboard.Get("panels").GetIndex(0).Get("libraryPanel")
+	assert.Equal(t, "cpu-panel", libraryPanel.Get("uid").MustString())
+}
+
+func TestImportFiltered(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.addDatasource("prometheus", "prometheus")
+	sourceOrg.addDatasource("postgres", "postgres")
+	teamA := sourceOrg.addFolder("team-a", "Team A")
+	teamB := sourceOrg.addFolder("team-b", "Team B")
+	sourceOrg.addFolder("empty", "Empty")
+	sourceOrg.addLibraryPanel("cpu-panel", "CPU", teamB)
+	sourceOrg.addLibraryPanel("unused-panel", "Unused", nil)
+	sourceOrg.addDashboard("a-dash", "A", teamA)
+	sourceOrg.addDashboard("a-experiment", "A Experiment", teamA).Dashboard.Set("tags", []interface{}{"experimental"})
+	sourceOrg.addDashboard("b-dash", "B", teamB).Dashboard.Set("panels", []interface{}{
+		map[string]interface{}{"id": 1, "type": "row", "panels": []interface{}{
+			map[string]interface{}{"id": 2, "libraryPanel": map[string]interface{}{"uid": "cpu-panel", "name": "CPU"}},
+		}},
+	})
+	sourceOrg.addDashboard("general-dash", "General", nil)
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.libraryElements = []*grafsdk.LibraryElement{}
+	assert.NoError(t, runImport(t, target, "-src", src, "-include", "folder:Team A", "-include", "dashboard:b-*", "-include", "datasource:prom*", "-exclude", "tag:experimental"))
+
+	assert.Len(t, targetOrg.datasources, 1)
+	assert.Equal(t, "prometheus", targetOrg.datasources[0].Name)
+	// only the folders and library panels of the selected dashboards are created
+	titles := []string{}
+	for _, folder := range targetOrg.folders {
+		titles = append(titles, folder.Title)
+	}
+	assert.ElementsMatch(t, []string{"Team A", "Team B"}, titles)
+	assert.Len(t, targetOrg.libraryElements, 1)
+	assert.NotNil(t, targetOrg.libraryElement("cpu-panel"))
+	assert.NotNil(t, targetOrg.dashboard("a-dash"))
+	assert.NotNil(t, targetOrg.dashboard("b-dash"))
+	assert.Nil(t, targetOrg.dashboard("a-experiment"))
+	assert.Nil(t, targetOrg.dashboard("general-dash"))
+
+	assert.Error(t, runImport(t, target, "-src", src, "-include", "panel:cpu"))
+}
+
+func TestImportExcludeOnly(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	teamA := sourceOrg.addFolder("team-a", "Team A")
+	teamB := sourceOrg.addFolder("team-b", "Team B")
+	sourceOrg.addFolder("empty", "Empty")
+	sourceOrg.addLibraryPanel("unused-panel", "Unused", nil)
+	sourceOrg.addDashboard("a-dash", "A", teamA)
+	sourceOrg.addDashboard("a-experiment", "A Experiment", teamA).Dashboard.Set("tags", []interface{}{"experimental"})
+	sourceOrg.addDashboard("b-dash", "B", teamB)
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.libraryElements = []*grafsdk.LibraryElement{}
+	assert.NoError(t, runImport(t, target, "-src", src, "-exclude", "tag:experimental", "-exclude", "folder:Team B"))
+
+	// excludes alone restore the empty folders and unused library panels like a full import
+	titles := []string{}
+	for _, folder := range targetOrg.folders {
+		titles = append(titles, folder.Title)
+	}
+	assert.ElementsMatch(t, []string{"Team A", "Empty"}, titles)
+	assert.NotNil(t, targetOrg.libraryElement("unused-panel"))
+	assert.NotNil(t, targetOrg.dashboard("a-dash"))
+	assert.Nil(t, targetOrg.dashboard("a-experiment"))
+	assert.Nil(t, targetOrg.dashboard("b-dash"))
+}
+
+func TestImportNestedFolders(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	team := sourceOrg.addFolder("team", "Team")
+	ops := sourceOrg.addFolder("ops", "Ops")
+	critical := sourceOrg.addSubfolder("critical", "Critical", sourceOrg.addSubfolder("team-alerts", "Alerts", team))
+	opsAlerts := sourceOrg.addSubfolder("ops-alerts", "Alerts", ops)
+	sourceOrg.addDashboard("critical-dash", "Critical", critical)
+	sourceOrg.addDashboard("ops-dash", "Ops", opsAlerts)
+
+	// the folder tree is backed up top-down
+	folders, err := source.client().fetchFolderTree(context.Background())
+	assert.NoError(t, err)
+	uids := []string{}
+	for _, folder := range folders {
+		uids = append(uids, folder.UID+":"+folder.ParentUID)
+	}
+	assert.Equal(t, []string{"team:", "ops:", "team-alerts:team", "ops-alerts:ops", "critical:team-alerts"}, uids)
+	src := backupFile(t, source, BackupOptions{})
+
+	// the ops folder exists in the target with another uid
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.addFolder("other-ops", "Ops")
+	assert.NoError(t, runImport(t, target, "-src", src))
+
+	parents := map[string]string{}
+	for _, folder := range targetOrg.folders {
+		parents[folder.UID] = folder.ParentUID
+	}
+	assert.Equal(t, map[string]string{
+		"other-ops":   "",
+		"team":        "",
+		"team-alerts": "team",
+		"critical":    "team-alerts",
+		"ops-alerts":  "other-ops",
+	}, parents)
+	assert.Equal(t, "critical", targetOrg.dashboard("critical-dash").Meta.Get("folderUid").MustString())
+	assert.Equal(t, "ops-alerts", targetOrg.dashboard("ops-dash").Meta.Get("folderUid").MustString())
+
+	// a second import finds every folder by uid
+	assert.NoError(t, runImport(t, target, "-src", src))
+	assert.Len(t, targetOrg.folders, 5)
+}
@@ -0,0 +1,71 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+)
+
+// backupFormatVersion is the version of the archive format, archives written before the manifest have no version
+const backupFormatVersion = 2
+
+// BackupManifest describes a backup archive, it is the last member of the archive since the checksums are only
+// known once every item was written
+type BackupManifest struct {
+	FormatVersion  int             `json:"formatVersion"`
+	GrafctlVersion string          `json:"grafctlVersion"`
+	Source         string          `json:"source"`
+	Created        time.Time       `json:"created"`
+	Grafana        *grafsdk.Health `json:"grafana,omitempty"`
+	// Sections has the checksums of every backup section in the order of the archive
+	Sections []*ManifestSection `json:"sections"`
+}
+
+// ManifestSection has the org and the item checksums of a backup section
+type ManifestSection struct {
+	Org *grafsdk.Org `json:"org,omitempty"`
+	// Checksums maps the items of the section, eg; dashboard/<uid>, to the SHA-256 of their JSON encoding
+	Checksums map[string]string `json:"checksums"`
+}
+
+func newManifestSection(org *grafsdk.Org) *ManifestSection {
+	return &ManifestSection{Org: org, Checksums: map[string]string{}}
+}
+
+// checksum returns the hex encoded SHA-256 of the JSON encoding of an item
+func checksum(by []byte) string {
+	sum := sha256.Sum256(by)
+	return hex.EncodeToString(sum[:])
+}
+
+// itemChecksum returns the checksum of the JSON encoding of v
+func itemChecksum(v interface{}) (string, error) {
+	by, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return checksum(by), nil
+}
+
+// datasourceKey returns the manifest key of a datasource, datasources of old grafana versions have no uid
+func datasourceKey(datasource *grafsdk.Datasource) string {
+	if datasource.UID == "" {
+		return "datasource/name:" + datasource.Name
+	}
+	return "datasource/" + datasource.UID
+}
+
+func folderKey(folder *grafsdk.Folder) string {
+	return "folder/" + folder.UID
+}
+
+func libraryElementKey(element *grafsdk.LibraryElement) string {
+	return "library-element/" + element.UID
+}
+
+func dashboardKey(dashboard *grafsdk.DashboardWithMeta) string {
+	return "dashboard/" + dashboard.Dashboard.Get("uid").MustString()
+}
@@ -0,0 +1,190 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+)
+
+// ResourcePermissions has the permissions granted on a folder or a dashboard, permissions a dashboard inherits from
+// its folder are not part of it
+type ResourcePermissions struct {
+	UID         string                `json:"uid"`
+	Permissions []*grafsdk.Permission `json:"permissions"`
+}
+
+func folderPermissionsKey(permissions *ResourcePermissions) string {
+	return "folder-permissions/" + permissions.UID
+}
+
+func dashboardPermissionsKey(permissions *ResourcePermissions) string {
+	return "dashboard-permissions/" + permissions.UID
+}
+
+// permissionsUnavailable reports whether the permissions can't be read, eg; credentials without the admin role
+func permissionsUnavailable(err error) bool {
+	return grafsdk.IsNotFound(err) || grafsdk.IsUnauthorized(err)
+}
+
+// fetchPermissions fetches the permissions of every uid, resources whose permissions can't be read are skipped with a
+// warning and dashboards with only inherited permissions are left out
+func (c *Client) fetchPermissions(ctx context.Context, kind string, uids []string, concurrency int, get func(ctx context.Context, uid string) ([]*grafsdk.Permission, error)) ([]*ResourcePermissions, error) {
+	var skipped int64
+	resourcePermissions := []*ResourcePermissions{}
+	if err := fetchOrdered(ctx, uids, concurrency, func(ctx context.Context, uid string) (*ResourcePermissions, error) {
+		permissions, err := get(ctx, uid)
+		if permissionsUnavailable(err) {
+			atomic.AddInt64(&skipped, 1)
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get %s permissions %s: %w", kind, uid, err)
+		}
+		own := []*grafsdk.Permission{}
+		for _, permission := range permissions {
+			if !permission.Inherited {
+				own = append(own, permission)
+			}
+		}
+		return &ResourcePermissions{UID: uid, Permissions: own}, nil
+	}, func(permissions *ResourcePermissions) error {
+		if permissions != nil {
+			resourcePermissions = append(resourcePermissions, permissions)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		log.Printf("warning: skipping the permissions of %d %s(s), reading permissions requires the admin role", skipped, kind)
+	}
+	return resourcePermissions, nil
+}
+
+func (c *Client) fetchFolderPermissions(ctx context.Context, folders []*grafsdk.Folder, concurrency int) ([]*ResourcePermissions, error) {
+	uids := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		uids = append(uids, folder.UID)
+	}
+	return c.fetchPermissions(ctx, "folder", uids, concurrency, c.GetFolderPermissions)
+}
+
+// fetchDashboardPermissions fetches the own permissions of the dashboards, dashboards with an empty acl are kept so the
+// import locks them down again rather than leaving them with the default role permissions
+func (c *Client) fetchDashboardPermissions(ctx context.Context, uids []string, hasACL map[string]bool, concurrency int) ([]*ResourcePermissions, error) {
+	permissions, err := c.fetchPermissions(ctx, "dashboard", uids, concurrency, c.GetDashboardPermissions)
+	if err != nil {
+		return nil, err
+	}
+	// most dashboards only inherit the permissions of their folder
+	own := []*ResourcePermissions{}
+	for _, dashboardPermissions := range permissions {
+		if len(dashboardPermissions.Permissions) > 0 || hasACL[dashboardPermissions.UID] {
+			own = append(own, dashboardPermissions)
+		}
+	}
+	return own, nil
+}
+
+// principalResolver maps the users and teams of backup permissions to the users and teams of the target org by
+// login and name, the numeric ids differ between grafana instances
+type principalResolver struct {
+	client *Client
+	teams  map[string]int64
+	users  map[string]int64
+}
+
+func newPrincipalResolver(client *Client) *principalResolver {
+	return &principalResolver{client: client}
+}
+
+// load lists the teams and users of the org on first use
+func (r *principalResolver) load(ctx context.Context) error {
+	if r.teams != nil {
+		return nil
+	}
+	teams, err := r.client.SearchTeams(ctx)
+	if err != nil {
+		return fmt.Errorf("SearchTeams: %w", err)
+	}
+	users, err := r.client.ListOrgUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("ListOrgUsers: %w", err)
+	}
+	r.teams = map[string]int64{}
+	for _, team := range teams {
+		r.teams[team.Name] = team.ID
+	}
+	r.users = map[string]int64{}
+	for _, user := range users {
+		r.users[user.Login] = user.UserID
+	}
+	return nil
+}
+
+// items returns the permission items granting the permissions in the target org.
+// Permissions of users and teams missing from the target org are skipped with a warning.
+func (r *principalResolver) items(ctx context.Context, resource string, permissions []*grafsdk.Permission) ([]*grafsdk.PermissionItem, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+	items := []*grafsdk.PermissionItem{}
+	for _, permission := range permissions {
+		item := grafsdk.PermissionItem{Role: permission.Role, Permission: permission.Permission}
+		switch {
+		case permission.UserLogin != "":
+			userID, ok := r.users[permission.UserLogin]
+			if !ok {
+				log.Printf("warning: %s: user %q does not exist, skipping its permission", resource, permission.UserLogin)
+				continue
+			}
+			item.UserID = userID
+		case permission.Team != "":
+			teamID, ok := r.teams[permission.Team]
+			if !ok {
+				log.Printf("warning: %s: team %q does not exist, skipping its permission", resource, permission.Team)
+				continue
+			}
+			item.TeamID = teamID
+		case permission.Role == "":
+			continue
+		}
+		items = append(items, &item)
+	}
+	return items, nil
+}
+
+// importFolderPermissions replaces the permissions of a restored folder with the permissions of its backup folder
+func (i *importer) importFolderPermissions(ctx context.Context, permissions *ResourcePermissions, folderUID string) error {
+	items, err := i.principals.items(ctx, "folder "+folderUID, permissions.Permissions)
+	if err != nil {
+		return err
+	}
+	if err := i.client.UpdateFolderPermissions(ctx, folderUID, items); err != nil {
+		return fmt.Errorf("UpdateFolderPermissions %s: %w", folderUID, err)
+	}
+	i.journal.notRolledBack("folder permissions", folderUID)
+	i.conf.logd("imported the permissions of folder %s", folderUID)
+	return nil
+}
+
+// importDashboardPermissions replaces the permissions of the restored dashboards, dashboards keep their uid
+func (i *importer) importDashboardPermissions(ctx context.Context, dashboardPermissions []*ResourcePermissions) error {
+	for _, permissions := range dashboardPermissions {
+		items, err := i.principals.items(ctx, "dashboard "+permissions.UID, permissions.Permissions)
+		if err != nil {
+			return err
+		}
+		if err := i.client.UpdateDashboardPermissions(ctx, permissions.UID, items); err != nil {
+			return fmt.Errorf("UpdateDashboardPermissions %s: %w", permissions.UID, err)
+		}
+		i.journal.notRolledBack("dashboard permissions", permissions.UID)
+	}
+	if len(dashboardPermissions) > 0 {
+		i.conf.logd("imported the permissions of %d dashboard(s)", len(dashboardPermissions))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportPermissions(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.enablePermissions()
+	sourceOrg.addTeam(10, "sre")
+	sourceOrg.addUser(20, "alice")
+	sourceOrg.addUser(21, "bob")
+	folder := sourceOrg.addFolder("ops-folder", "Ops")
+	sourceOrg.addDashboard("ops-dash", "Ops", folder)
+	sourceOrg.addDashboard("inherited-dash", "Inherited", folder)
+	sourceOrg.folderPermissions["ops-folder"] = []*grafsdk.Permission{
+		{TeamID: 10, Team: "sre", Permission: grafsdk.PermissionAdmin},
+		{Role: "Viewer", Permission: grafsdk.PermissionView},
+	}
+	sourceOrg.dashboardPermissions["ops-dash"] = []*grafsdk.Permission{
+		{TeamID: 10, Team: "sre", Permission: grafsdk.PermissionAdmin, Inherited: true},
+		{UserID: 20, UserLogin: "alice", Permission: grafsdk.PermissionEdit},
+		{UserID: 21, UserLogin: "bob", Permission: grafsdk.PermissionView},
+	}
+	sourceOrg.dashboardPermissions["inherited-dash"] = []*grafsdk.Permission{
+		{TeamID: 10, Team: "sre", Permission: grafsdk.PermissionAdmin, Inherited: true},
+	}
+	// an empty acl locks the dashboard down to admins
+	sourceOrg.addDashboard("locked-dash", "Locked", nil).Meta.Set("hasAcl", true)
+	sourceOrg.dashboardPermissions["locked-dash"] = []*grafsdk.Permission{}
+	src := backupFile(t, source, BackupOptions{})
+
+	// the target has the same team and user with other ids, bob does not exist
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.enablePermissions()
+	targetOrg.addTeam(3, "sre")
+	targetOrg.addUser(7, "alice")
+	assert.NoError(t, runImport(t, target, "-src", src))
+
+	targetFolder := targetOrg.folders[0]
+	assert.Equal(t, []*grafsdk.Permission{
+		{TeamID: 3, Team: "sre", Permission: grafsdk.PermissionAdmin},
+		{Role: "Viewer", Permission: grafsdk.PermissionView},
+	}, targetOrg.folderPermissions[targetFolder.UID])
+	assert.Equal(t, []*grafsdk.Permission{
+		{UserID: 7, UserLogin: "alice", Permission: grafsdk.PermissionEdit},
+	}, targetOrg.dashboardPermissions["ops-dash"])
+	assert.NotContains(t, targetOrg.dashboardPermissions, "inherited-dash")
+	assert.Contains(t, targetOrg.dashboardPermissions, "locked-dash")
+	assert.Empty(t, targetOrg.dashboardPermissions["locked-dash"])
+}
@@ -0,0 +1,222 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/diogogmt/grafctl/pkg/simplejson"
+	"gopkg.in/yaml.v2"
+)
+
+// loadDatasourceMap reads the -ds-map file mapping the name or uid of backup datasources to the name or uid of target
+// datasources, eg; prometheus-staging: prometheus-prod
+func loadDatasourceMap(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+	dsMap := map[string]string{}
+	if err := yaml.NewDecoder(f).Decode(&dsMap); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("yaml.Decode %s: %w", path, err)
+	}
+	return dsMap, nil
+}
+
+// mappedDatasource returns the name or uid of the target datasource the ds map maps a backup datasource to, empty when
+// the backup datasource is not mapped to another datasource. Mapped datasources are not restored.
+func mappedDatasource(dsMap map[string]string, datasource *grafsdk.Datasource) string {
+	for _, key := range []string{datasource.UID, datasource.Name} {
+		mapped, ok := dsMap[key]
+		if !ok {
+			continue
+		}
+		if mapped == datasource.UID || mapped == datasource.Name {
+			return ""
+		}
+		return mapped
+	}
+	return ""
+}
+
+// datasourceRemapper rewrites the datasource references of dashboards from the backup datasources to the target
+// datasources, the uids of datasources with the same name differ between grafana instances
+type datasourceRemapper struct {
+	targets []*grafsdk.Datasource
+	byUID   map[string]*grafsdk.Datasource
+	byName  map[string]*grafsdk.Datasource
+}
+
+// newDatasourceRemapper maps every backup datasource to the target datasource of the ds map, or with the same name
+func newDatasourceRemapper(backup []*grafsdk.Datasource, targets []*grafsdk.Datasource, dsMap map[string]string) (*datasourceRemapper, error) {
+	r := datasourceRemapper{
+		targets: targets,
+		byUID:   map[string]*grafsdk.Datasource{},
+		byName:  map[string]*grafsdk.Datasource{},
+	}
+	for _, datasource := range backup {
+		target := r.target(datasource.Name)
+		for _, key := range []string{datasource.UID, datasource.Name} {
+			mapped, ok := dsMap[key]
+			if !ok {
+				continue
+			}
+			if target = r.target(mapped); target == nil {
+				return nil, fmt.Errorf("-ds-map: datasource %q does not exist in the target grafana", mapped)
+			}
+			break
+		}
+		if target == nil {
+			continue
+		}
+		r.byUID[datasource.UID] = target
+		r.byName[datasource.Name] = target
+	}
+	return &r, nil
+}
+
+// target returns the target datasource with the name or uid
+func (r *datasourceRemapper) target(nameOrUID string) *grafsdk.Datasource {
+	for _, datasource := range r.targets {
+		if datasource.Name == nameOrUID {
+			return datasource
+		}
+	}
+	for _, datasource := range r.targets {
+		if datasource.UID == nameOrUID {
+			return datasource
+		}
+	}
+	return nil
+}
+
+// resolve returns the target datasource of a reference to a backup datasource uid or name, or to a ${DS_*} input
+func (r *datasourceRemapper) resolve(ref string, inputs map[string]*grafsdk.Datasource) *grafsdk.Datasource {
+	if strings.HasPrefix(ref, "${") && strings.HasSuffix(ref, "}") {
+		return inputs[strings.TrimSuffix(strings.TrimPrefix(ref, "${"), "}")]
+	}
+	if target, ok := r.byUID[ref]; ok {
+		return target
+	}
+	return r.byName[ref]
+}
+
+// reference returns the string reference to the target datasource replacing ref, references by uid stay references
+// by uid and the other references are by name
+func (r *datasourceRemapper) reference(ref string, target *grafsdk.Datasource) string {
+	if _, ok := r.byUID[ref]; ok && r.byName[ref] == nil {
+		return target.UID
+	}
+	return target.Name
+}
+
+// inputs resolves the datasource inputs of a dashboard exported for sharing, eg; ${DS_PROMETHEUS}. Inputs are matched
+// by the datasource name of their label, or to the only target datasource of their plugin.
+func (r *datasourceRemapper) inputs(dashboard *simplejson.Json) map[string]*grafsdk.Datasource {
+	inputs := map[string]*grafsdk.Datasource{}
+	for _, in := range dashboard.Get("__inputs").MustArray() {
+		input := simplejson.NewFromAny(in)
+		if input.Get("type").MustString() != "datasource" {
+			continue
+		}
+		label := input.Get("label").MustString()
+		target := r.byName[label]
+		if target == nil {
+			target = r.target(label)
+		}
+		if target == nil {
+			pluginID := input.Get("pluginId").MustString()
+			for _, datasource := range r.targets {
+				if datasource.Type != pluginID {
+					continue
+				}
+				if target != nil {
+					// the plugin has several datasources, the input is ambiguous
+					target = nil
+					break
+				}
+				target = datasource
+			}
+		}
+		if target != nil {
+			inputs[input.Get("name").MustString()] = target
+		}
+	}
+	return inputs
+}
+
+// rewrite rewrites every datasource reference of a dashboard or panel model and returns the number of rewritten
+// references. Object references get the target uid and type, legacy string references the target name.
+func (r *datasourceRemapper) rewrite(model *simplejson.Json) int {
+	inputs := r.inputs(model)
+	rewritten := r.walk(model.Interface(), inputs)
+
+	// datasource template variables select the datasource by name or uid
+	for _, v := range model.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(v)
+		if variable.Get("type").MustString() != "datasource" {
+			continue
+		}
+		current := variable.Get("current")
+		value, ok := current.Get("value").Interface().(string)
+		if !ok {
+			continue
+		}
+		if target := r.resolve(value, inputs); target != nil {
+			current.Set("value", r.reference(value, target))
+			current.Set("text", target.Name)
+			rewritten++
+		}
+	}
+
+	// every input is resolved once the references are rewritten
+	if len(inputs) > 0 {
+		remaining := []interface{}{}
+		for _, in := range model.Get("__inputs").MustArray() {
+			if _, ok := inputs[simplejson.NewFromAny(in).Get("name").MustString()]; !ok {
+				remaining = append(remaining, in)
+			}
+		}
+		if len(remaining) == 0 {
+			model.Del("__inputs")
+		} else {
+			model.Set("__inputs", remaining)
+		}
+	}
+	return rewritten
+}
+
+func (r *datasourceRemapper) walk(node interface{}, inputs map[string]*grafsdk.Datasource) int {
+	rewritten := 0
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if key != "datasource" {
+				rewritten += r.walk(value, inputs)
+				continue
+			}
+			switch ref := value.(type) {
+			case string:
+				if target := r.resolve(ref, inputs); target != nil {
+					v[key] = r.reference(ref, target)
+					rewritten++
+				}
+			case map[string]interface{}:
+				uid, _ := ref["uid"].(string)
+				if target := r.resolve(uid, inputs); target != nil {
+					ref["uid"] = target.UID
+					ref["type"] = target.Type
+					rewritten++
+				}
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			rewritten += r.walk(value, inputs)
+		}
+	}
+	return rewritten
+}
@@ -0,0 +1,109 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/diogogmt/grafctl/pkg/simplejson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatasourceRemapper(t *testing.T) {
+	backup := []*grafsdk.Datasource{
+		{UID: "staging-prom", Name: "Prometheus", Type: "prometheus"},
+		{UID: "staging-loki", Name: "Loki", Type: "loki"},
+		{UID: "staging-tempo", Name: "Tempo", Type: "tempo"},
+	}
+	targets := []*grafsdk.Datasource{
+		{UID: "prod-prom", Name: "Prometheus", Type: "prometheus"},
+		{UID: "prod-loki", Name: "Loki Prod", Type: "loki"},
+	}
+	remapper, err := newDatasourceRemapper(backup, targets, map[string]string{"Loki": "prod-loki"})
+	assert.NoError(t, err)
+
+	dashboard, err := simplejson.NewJson([]byte(`{
+		"__inputs": [{"name": "DS_PROM", "label": "Prometheus", "type": "datasource", "pluginId": "prometheus"}],
+		"panels": [
+			{"datasource": {"type": "prometheus", "uid": "staging-prom"}, "targets": [{"datasource": {"uid": "staging-prom"}}]},
+			{"datasource": "Loki", "targets": [{"datasource": "staging-loki"}]},
+			{"datasource": {"type": "prometheus", "uid": "${DS_PROM}"}},
+			{"type": "row", "panels": [{"datasource": "${DS_PROM}"}]},
+			{"datasource": "-- Mixed --", "targets": [{"datasource": "$ds"}, {"datasource": {"uid": "staging-tempo"}}]}
+		],
+		"templating": {"list": [
+			{"type": "datasource", "name": "ds", "query": "prometheus", "current": {"text": "Prometheus", "value": "Prometheus"}},
+			{"type": "query", "name": "job", "datasource": {"type": "loki", "uid": "staging-loki"}}
+		]}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, remapper.rewrite(dashboard))
+
+	panels := dashboard.Get("panels")
+	assert.Equal(t, "prod-prom", panels.GetIndex(0).GetPath("datasource", "uid").MustString())
+	assert.Equal(t, "prod-prom", panels.GetIndex(0).Get("targets").GetIndex(0).GetPath("datasource", "uid").MustString())
+	assert.Equal(t, "Loki Prod", panels.GetIndex(1).Get("datasource").MustString())
+	assert.Equal(t, "prod-loki", panels.GetIndex(1).Get("targets").GetIndex(0).Get("datasource").MustString())
+	assert.Equal(t, "prod-prom", panels.GetIndex(2).GetPath("datasource", "uid").MustString())
+	assert.Equal(t, "Prometheus", panels.GetIndex(3).Get("panels").GetIndex(0).Get("datasource").MustString())
+	// template variables, special and unknown datasources are left untouched
+	assert.Equal(t, "-- Mixed --", panels.GetIndex(4).Get("datasource").MustString())
+	assert.Equal(t, "$ds", panels.GetIndex(4).Get("targets").GetIndex(0).Get("datasource").MustString())
+	assert.Equal(t, "staging-tempo", panels.GetIndex(4).Get("targets").GetIndex(1).GetPath("datasource", "uid").MustString())
+
+	variables := dashboard.GetPath("templating", "list")
+	assert.Equal(t, "Prometheus", variables.GetIndex(0).GetPath("current", "value").MustString())
+	assert.Equal(t, "loki", variables.GetIndex(1).GetPath("datasource", "type").MustString())
+	assert.Equal(t, "prod-loki", variables.GetIndex(1).GetPath("datasource", "uid").MustString())
+	_, ok := dashboard.CheckGet("__inputs")
+	assert.False(t, ok)
+
+	_, err = newDatasourceRemapper(backup, targets, map[string]string{"staging-tempo": "Tempo Prod"})
+	assert.EqualError(t, err, `-ds-map: datasource "Tempo Prod" does not exist in the target grafana`)
+}
+
+func TestImportDatasourceMap(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.addDatasource("staging-prometheus", "prometheus")
+	sourceOrg.addDashboard("main-dash", "Main", nil).Dashboard.Set("panels", []interface{}{
+		map[string]interface{}{"id": 1, "datasource": map[string]interface{}{"type": "prometheus", "uid": "staging-prometheus-uid"}},
+	})
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.addDatasource("prometheus", "prometheus")
+	dsMap := filepath.Join(t.TempDir(), "ds-map.yaml")
+	assert.NoError(t, os.WriteFile(dsMap, []byte("staging-prometheus: prometheus\n"), 0600))
+	assert.NoError(t, runImport(t, target, "-src", src, "-ds-map", dsMap))
+
+	// the mapped datasource is not restored next to the datasource it is mapped to
+	assert.Len(t, targetOrg.datasources, 1)
+	assert.Equal(t, "prometheus", targetOrg.datasources[0].Name)
+	panel := targetOrg.dashboard("main-dash").Dashboard.Get("panels").GetIndex(0)
+	assert.Equal(t, "prometheus-uid", panel.GetPath("datasource", "uid").MustString())
+}
+
+func TestImportKeepsDatasourceUID(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.addDatasource("prometheus", "prometheus").UID = "staging-prometheus-uid"
+	sourceOrg.addDashboard("main-dash", "Main", nil).Dashboard.Set("panels", []interface{}{
+		map[string]interface{}{"id": 1, "datasource": map[string]interface{}{"type": "prometheus", "uid": "staging-prometheus-uid"}},
+	})
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.addDatasource("prometheus", "prometheus")
+	assert.NoError(t, runImport(t, target, "-src", src))
+
+	// the datasource matched by name keeps its target uid, the dashboards of both instances resolve it
+	assert.Len(t, targetOrg.datasources, 1)
+	assert.Equal(t, "prometheus-uid", targetOrg.datasources[0].UID)
+	panel := targetOrg.dashboard("main-dash").Dashboard.Get("panels").GetIndex(0)
+	assert.Equal(t, "prometheus-uid", panel.GetPath("datasource", "uid").MustString())
+	assert.NoError(t, runImport(t, target, "-src", src, "-dry-run"))
+}
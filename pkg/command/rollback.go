@@ -0,0 +1,161 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/olekukonko/tablewriter"
+)
+
+// journalEntry undoes a single change made by an import, changes without undo can't be rolled back
+type journalEntry struct {
+	kind   string
+	name   string
+	action string
+	undo   func(ctx context.Context) error
+}
+
+// importJournal records the prior state of the datasources, folders, library panels and dashboards changed by an
+// import, or that they were created, so a failed import can be rolled back. The other changes, eg; permissions and
+// alerting resources, are recorded as not rolled back. A nil journal records nothing.
+type importJournal struct {
+	entries []*journalEntry
+}
+
+func (j *importJournal) add(entry *journalEntry) {
+	if j == nil {
+		return
+	}
+	j.entries = append(j.entries, entry)
+}
+
+// datasource records a change of the datasource, prior is nil for created datasources
+func (j *importJournal) datasource(client *Client, name string, prior *grafsdk.Datasource) {
+	if prior == nil {
+		j.add(&journalEntry{kind: "datasource", name: name, action: "delete", undo: func(ctx context.Context) error {
+			if err := client.DeleteDatasourceByName(ctx, name); err != nil {
+				return fmt.Errorf("DeleteDatasourceByName %s: %w", name, err)
+			}
+			return nil
+		}})
+		return
+	}
+	j.add(&journalEntry{kind: "datasource", name: name, action: "restore", undo: func(ctx context.Context) error {
+		if err := client.UpdateDatasource(ctx, prior); err != nil {
+			return fmt.Errorf("UpdateDatasource %d %s: %w", prior.ID, name, err)
+		}
+		return nil
+	}})
+}
+
+// notRolledBack records a change the rollback can't undo so the rollback reports it
+func (j *importJournal) notRolledBack(kind string, name string) {
+	j.add(&journalEntry{kind: kind, name: name, action: "none"})
+}
+
+// partial returns the number of recorded changes the rollback can't undo
+func (j *importJournal) partial() int {
+	n := 0
+	for _, entry := range j.entries {
+		if entry.undo == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// folder records a created folder, existing folders are never changed by an import
+func (j *importJournal) folder(client *Client, folder *grafsdk.Folder) {
+	j.add(&journalEntry{kind: "folder", name: folder.Title, action: "delete", undo: func(ctx context.Context) error {
+		if err := client.DeleteFolder(ctx, folder.UID); err != nil {
+			return fmt.Errorf("DeleteFolder %s: %w", folder.UID, err)
+		}
+		return nil
+	}})
+}
+
+// libraryElement records a change of the library element, prior is nil for created elements
+func (j *importJournal) libraryElement(client *Client, uid string, name string, prior *grafsdk.LibraryElement) {
+	if prior == nil {
+		j.add(&journalEntry{kind: "library panel", name: name, action: "delete", undo: func(ctx context.Context) error {
+			if err := client.DeleteLibraryElementByUID(ctx, uid); err != nil {
+				return fmt.Errorf("DeleteLibraryElementByUID %s: %w", uid, err)
+			}
+			return nil
+		}})
+		return
+	}
+	j.add(&journalEntry{kind: "library panel", name: name, action: fmt.Sprintf("restore version %d", prior.Version), undo: func(ctx context.Context) error {
+		// updates must send the current version
+		current, err := client.GetLibraryElementByUID(ctx, uid)
+		if err != nil {
+			return fmt.Errorf("GetLibraryElementByUID %s: %w", uid, err)
+		}
+		prior.Version = current.Version
+		prior.Meta = nil
+		if _, err := client.UpdateLibraryElement(ctx, prior); err != nil {
+			return fmt.Errorf("UpdateLibraryElement %s: %w", uid, err)
+		}
+		return nil
+	}})
+}
+
+// dashboard records a change of the dashboard, prior is nil for created dashboards
+func (j *importJournal) dashboard(client *Client, uid string, prior *grafsdk.DashboardWithMeta) {
+	if prior == nil {
+		j.add(&journalEntry{kind: "dashboard", name: uid, action: "delete", undo: func(ctx context.Context) error {
+			if err := client.DeleteDashboardByUID(ctx, uid); err != nil {
+				return fmt.Errorf("DeleteDashboardByUID %s: %w", uid, err)
+			}
+			return nil
+		}})
+		return
+	}
+	version := prior.Dashboard.Get("version").MustInt64()
+	j.add(&journalEntry{kind: "dashboard", name: uid, action: fmt.Sprintf("restore version %d", version), undo: func(ctx context.Context) error {
+		// the numeric id and version are the ones of the current dashboard
+		prior.Dashboard.Del("id")
+		prior.Dashboard.Del("version")
+		if err := client.SaveDashboard(ctx, &grafsdk.DashboardSavePayload{
+			Dashboard: prior.Dashboard,
+			Overwrite: true,
+			FolderID:  prior.Meta.Get("folderId").MustInt64(),
+			FolderUID: prior.Meta.Get("folderUid").MustString(),
+		}); err != nil {
+			return fmt.Errorf("SaveDashboard %s: %w", uid, err)
+		}
+		return nil
+	}})
+}
+
+// rollback undoes the recorded changes in reverse order and prints what was undone and what can't be, changes
+// failing to be undone are reported and do not stop the rollback
+func (j *importJournal) rollback(ctx context.Context, w io.Writer) error {
+	failed := 0
+	partial := j.partial()
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Kind", "Name", "Action", "Result"})
+	for n := len(j.entries) - 1; n >= 0; n-- {
+		entry := j.entries[n]
+		result := "ok"
+		if entry.undo == nil {
+			result = "not rolled back"
+		} else if err := entry.undo(ctx); err != nil {
+			result = err.Error()
+			failed++
+		}
+		table.Append([]string{entry.kind, entry.name, entry.action, result})
+	}
+	if partial > 0 {
+		fmt.Fprintf(w, "Rolled back %d change(s), %d change(s) can't be rolled back:\n", len(j.entries)-failed-partial, partial)
+	} else {
+		fmt.Fprintf(w, "Rolled back %d change(s):\n", len(j.entries)-failed)
+	}
+	table.Render()
+	if failed > 0 {
+		return fmt.Errorf("rollback failed to undo %d change(s)", failed)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package command
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportRollback(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.addDatasource("prometheus", "prometheus").URL = "http://prometheus-new:9090"
+	sourceOrg.addDatasource("postgres", "postgres")
+	newFolder := sourceOrg.addFolder("new-folder", "New Folder")
+	sourceOrg.addDashboard("existing-dash", "New", nil)
+	sourceOrg.addDashboard("new-dash", "New", newFolder)
+	sourceOrg.addDashboard("general-dash", "General", nil)
+	sourceOrg.addDashboard("failing-dash", "Failing", nil)
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.addDatasource("prometheus", "prometheus").URL = "http://prometheus-old:9090"
+	targetOrg.addDashboard("existing-dash", "Old", targetOrg.addFolder("old-folder", "Old Folder")).Dashboard.Set("version", 3)
+	target.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/api/dashboards/db" {
+			return false
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "failing-dash") {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "database is locked"})
+			return true
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		return false
+	}
+
+	err := runImport(t, target, "-src", src, "-rollback")
+	assert.ErrorContains(t, err, "import rolled back")
+	assert.ErrorContains(t, err, "SaveDashboard failing-dash")
+
+	assert.Len(t, targetOrg.datasources, 1)
+	assert.Equal(t, "http://prometheus-old:9090", targetOrg.datasources[0].URL)
+	assert.Len(t, targetOrg.folders, 1)
+	assert.Equal(t, "Old Folder", targetOrg.folders[0].Title)
+	assert.Len(t, targetOrg.dashboards, 1)
+	existing := targetOrg.dashboard("existing-dash")
+	assert.Equal(t, "Old", existing.Dashboard.Get("title").MustString())
+	assert.Equal(t, "Old Folder", existing.Meta.Get("folderTitle").MustString())
+
+	// without -rollback the import leaves the changes made before the failure
+	err = runImport(t, target, "-src", src)
+	assert.ErrorContains(t, err, "SaveDashboard failing-dash")
+	assert.Len(t, targetOrg.datasources, 2)
+	assert.NotNil(t, targetOrg.dashboard("new-dash"))
+}
+
+func TestImportRollbackPartial(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.addLibraryPanel("new-panel", "New", nil)
+	sourceOrg.addLibraryPanel("existing-panel", "New", nil)
+	sourceOrg.enableAlerting()
+	sourceOrg.addAlertRuleGroup(sourceOrg.addFolder("alerts-folder", "Alerts"), "cpu", "rule-1")
+	sourceOrg.addDashboard("failing-dash", "Failing", nil)
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.libraryElements = []*grafsdk.LibraryElement{}
+	targetOrg.addLibraryPanel("existing-panel", "Old", nil).Version = 4
+	targetAlerting := targetOrg.enableAlerting()
+	target.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/api/dashboards/db" {
+			return false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "database is locked"})
+		return true
+	}
+
+	// the library panels are rolled back, the alerting resources are reported as not rolled back
+	err := runImport(t, target, "-src", src, "-rollback")
+	assert.ErrorContains(t, err, "import partially rolled back, 2 change(s) can't be rolled back")
+	assert.ErrorContains(t, err, "SaveDashboard failing-dash")
+	assert.Len(t, targetOrg.libraryElements, 1)
+	existing := targetOrg.libraryElement("existing-panel")
+	assert.Equal(t, "Old", existing.Name)
+	assert.Equal(t, int64(6), existing.Version)
+	assert.Len(t, targetAlerting.ruleGroups, 1)
+}
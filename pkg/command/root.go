@@ -3,20 +3,167 @@ package command
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/peterbourgon/ff/v2"
 	"github.com/peterbourgon/ff/v2/ffcli"
 )
 
 // RootConfig has the config for the root command
 type RootConfig struct {
-	APIURL  string
-	APIKey  string
-	Verbose bool
+	ConfigFile   string
+	Context      string
+	APIURL       string
+	APIKey       string
+	Auth         string
+	User         string
+	Password     string
+	PasswordFile string
+	Headers      stringsFlag
+	Cookies      stringsFlag
+	Org          string
+	Folder       string
+	Verbose      bool
+	Timeout      time.Duration
+	Retries      int
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	RateLimit    float64
+
+	CACert             string
+	ClientCert         string
+	ClientKey          string
+	TLSServerName      string
+	InsecureSkipVerify bool
+
+	S3Endpoint string
+	S3Region   string
+	S3Insecure bool
+
+	EncryptionPassphraseFile string
+	EncryptionKeyFile        string
+
+	client *Client
+}
+
+// Client returns the grafana client configured by the root flags, the client is created once and shared by all calls
+func (c *RootConfig) Client(ctx context.Context) (*Client, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+	opts, err := c.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(c.APIURL, c.APIKey, c.Verbose, opts...)
+	if c.Org != "" {
+		org, err := client.resolveOrg(ctx, c.Org)
+		if err != nil {
+			return nil, err
+		}
+		c.logd("using org %d:%q", org.ID, org.Name)
+		client = client.withOrg(org.ID)
+	}
+	c.client = client
+	return c.client, nil
+}
+
+// BackupStore returns the store of the backup location, the location scheme takes precedence over the provider,
+// eg; s3://bucket/prefix
+func (c *RootConfig) BackupStore(ctx context.Context, provider BackupProvider, location string) (BackupStore, error) {
+	provider, location = parseBackupLocation(provider, location)
+	return NewBackupStore(ctx, provider, location, S3Options{
+		Endpoint: c.S3Endpoint,
+		Region:   c.S3Region,
+		Insecure: c.S3Insecure,
+	})
+}
+
+// encryptionKey returns the key of the backup archives, nil when archives are not encrypted
+func (c *RootConfig) encryptionKey() (*EncryptionKey, error) {
+	return LoadEncryptionKey(c.EncryptionPassphraseFile, c.EncryptionKeyFile)
+}
+
+func (c *RootConfig) clientOptions() ([]grafsdk.Option, error) {
+	tlsConfig, err := grafsdk.NewTLSConfig(grafsdk.TLSOptions{
+		CAFile:             c.CACert,
+		CertFile:           c.ClientCert,
+		KeyFile:            c.ClientKey,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("NewTLSConfig: %w", err)
+	}
+	auth, err := c.authenticator()
+	if err != nil {
+		return nil, err
+	}
+	return []grafsdk.Option{
+		grafsdk.WithAuthenticator(auth),
+		grafsdk.WithTimeout(c.Timeout),
+		grafsdk.WithRetry(c.Retries, c.RetryWaitMin, c.RetryWaitMax),
+		grafsdk.WithRateLimit(c.RateLimit),
+		grafsdk.WithTLSConfig(tlsConfig),
+	}, nil
 }
 
-func (c *RootConfig) Client() *Client {
-	return NewClient(c.APIURL, c.APIKey, c.Verbose)
+// authenticator builds the credentials selected by -auth, extra -header values are sent with every mode
+func (c *RootConfig) authenticator() (grafsdk.Authenticator, error) {
+	headers := map[string]string{}
+	for _, header := range c.Headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid -header %q, expected name: value", header)
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	var auth grafsdk.Authenticator
+	switch c.Auth {
+	case "bearer":
+		if c.APIKey != "" {
+			auth = grafsdk.BearerAuth(c.APIKey)
+		}
+	case "basic":
+		password := c.Password
+		if c.PasswordFile != "" {
+			passwordBy, err := os.ReadFile(c.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("os.ReadFile: %w", err)
+			}
+			password = strings.TrimRight(string(passwordBy), "\r\n")
+		}
+		if c.User == "" || password == "" {
+			return nil, fmt.Errorf("basic auth requires -user and -password or -password-file")
+		}
+		auth = grafsdk.BasicAuth(c.User, password)
+	case "cookie":
+		cookies := []*http.Cookie{}
+		for _, cookie := range c.Cookies {
+			parts := strings.SplitN(cookie, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				return nil, fmt.Errorf("invalid -cookie %q, expected name=value", cookie)
+			}
+			cookies = append(cookies, &http.Cookie{Name: parts[0], Value: parts[1]})
+		}
+		if len(cookies) == 0 {
+			return nil, fmt.Errorf("cookie auth requires at least one -cookie")
+		}
+		auth = grafsdk.CookieAuth(cookies...)
+	case "none":
+		// nop, eg; an auth proxy identifying the user with -header
+	default:
+		return nil, fmt.Errorf("auth %q not supported", c.Auth)
+	}
+
+	return grafsdk.MultiAuth(auth, grafsdk.HeaderAuth(headers)), nil
 }
 
 // RootCmd wraps the  config and a ffcli.Command
@@ -46,11 +193,18 @@ func NewRootCmd(opts ...RootConfigOption) *RootCmd {
 		ShortUsage: "grafctl [flags] <subcommand>",
 		ShortHelp:  "Grafana control",
 		FlagSet:    fs,
-		Exec:       cmd.Exec,
+		Options: []ff.Option{
+			ff.WithConfigFileFlag("config"),
+			ff.WithConfigFileParser(conf.parseContextsFile),
+			ff.WithAllowMissingConfigFile(true),
+			ff.WithEnvVarPrefix(envVarPrefix),
+		},
+		Exec: cmd.Exec,
 		Subcommands: []*ffcli.Command{
 			NewDashboardCmd(&conf).Command,
 			NewBackupCmd(&conf).Command,
 			NewImportCmd(&conf).Command,
+			NewConfigCmd(&conf).Command,
 		},
 	}
 
@@ -61,9 +215,34 @@ func NewRootCmd(opts ...RootConfigOption) *RootCmd {
 
 // RegisterFlags registers a set of flags for the root command
 func (c *RootCmd) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Conf.ConfigFile, "config", DefaultConfigFile(), "grafctl config file with the named contexts")
+	fs.StringVar(&c.Conf.Context, "context", "", "name of the context to use, defaults to the current-context of the config file")
 	fs.StringVar(&c.Conf.APIURL, "url", "", "grafana server API URL")
-	fs.StringVar(&c.Conf.APIKey, "key", "", "grafana server API key")
+	fs.StringVar(&c.Conf.APIKey, "key", "", "grafana server API key or service account token")
+	fs.StringVar(&c.Conf.Auth, "auth", "bearer", "authentication mode, eg; bearer/basic/cookie/none")
+	fs.StringVar(&c.Conf.User, "user", "", "grafana user for basic auth")
+	fs.StringVar(&c.Conf.Password, "password", "", "grafana password for basic auth")
+	fs.StringVar(&c.Conf.PasswordFile, "password-file", "", "file with the grafana password for basic auth")
+	fs.Var(&c.Conf.Headers, "header", "extra header sent with every request, eg; X-WEBAUTH-USER: admin (repeatable)")
+	fs.Var(&c.Conf.Cookies, "cookie", "cookie sent with every request for cookie auth, eg; grafana_session=abc (repeatable)")
+	fs.StringVar(&c.Conf.Org, "org", "", "grafana organization id or name, defaults to the org of the credentials")
+	fs.StringVar(&c.Conf.Folder, "folder", "", "default folder title, scopes the dashboards listed by dash ls")
 	fs.BoolVar(&c.Conf.Verbose, "verbose", false, "log verbose output")
+	fs.DurationVar(&c.Conf.Timeout, "timeout", 120*time.Second, "timeout of a single grafana API request")
+	fs.IntVar(&c.Conf.Retries, "retries", 3, "number of times a failed grafana API request is retried")
+	fs.DurationVar(&c.Conf.RetryWaitMin, "retry-wait-min", time.Second, "minimum backoff between retries")
+	fs.DurationVar(&c.Conf.RetryWaitMax, "retry-wait-max", 30*time.Second, "maximum backoff between retries")
+	fs.Float64Var(&c.Conf.RateLimit, "rate-limit", 0, "maximum grafana API requests per second, 0 disables the limit")
+	fs.StringVar(&c.Conf.CACert, "ca-cert", "", "PEM bundle of certificate authorities used to verify the grafana server")
+	fs.StringVar(&c.Conf.ClientCert, "client-cert", "", "PEM client certificate used for mutual TLS")
+	fs.StringVar(&c.Conf.ClientKey, "client-key", "", "PEM client key used for mutual TLS")
+	fs.StringVar(&c.Conf.TLSServerName, "tls-server-name", "", "server name used to verify the grafana server certificate")
+	fs.BoolVar(&c.Conf.InsecureSkipVerify, "insecure-skip-verify", false, "skip the verification of the grafana server certificate")
+	fs.StringVar(&c.Conf.S3Endpoint, "s3-endpoint", "s3.amazonaws.com", "S3 API endpoint used by s3:// backups, eg; localhost:9000 for MinIO")
+	fs.StringVar(&c.Conf.S3Region, "s3-region", "", "region of the S3 bucket, detected from the bucket when empty")
+	fs.BoolVar(&c.Conf.S3Insecure, "s3-insecure", false, "reach the S3 endpoint over plain HTTP")
+	fs.StringVar(&c.Conf.EncryptionPassphraseFile, "encryption-passphrase-file", "", "file with the passphrase encrypting the backup archives")
+	fs.StringVar(&c.Conf.EncryptionKeyFile, "encryption-key-file", "", "file with the base64 encoded 256 bit key encrypting the backup archives")
 }
 
 // Exec executes the root command
@@ -78,3 +257,17 @@ func (c *RootConfig) logd(format string, args ...interface{}) {
 	}
 	log.Printf(format, args...)
 }
+
+// ExitError is an error terminating grafctl with a specific exit code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
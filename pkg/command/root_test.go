@@ -0,0 +1,43 @@
+package command
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootConfigAuthenticator(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(passwordFile, []byte("secret\n"), 0600))
+
+	conf := &RootConfig{
+		Auth:         "basic",
+		User:         "admin",
+		PasswordFile: passwordFile,
+		Headers:      stringsFlag{"X-WEBAUTH-USER: admin"},
+	}
+	auth, err := conf.authenticator()
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:3000/api/search", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, auth.Authenticate(req))
+	user, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "admin", user)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, "admin", req.Header.Get("X-WEBAUTH-USER"))
+
+	// invalid configurations
+	_, err = (&RootConfig{Auth: "basic", User: "admin"}).authenticator()
+	assert.Error(t, err)
+	_, err = (&RootConfig{Auth: "cookie"}).authenticator()
+	assert.Error(t, err)
+	_, err = (&RootConfig{Auth: "bearer", Headers: stringsFlag{"invalid"}}).authenticator()
+	assert.Error(t, err)
+	_, err = (&RootConfig{Auth: "oauth"}).authenticator()
+	assert.Error(t, err)
+}
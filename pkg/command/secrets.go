@@ -0,0 +1,134 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"gopkg.in/yaml.v2"
+)
+
+// DatasourceSecrets has the secrets of a datasource, grafana never returns them so backups don't have them
+type DatasourceSecrets struct {
+	Password          string            `yaml:"password"`
+	BasicAuthPassword string            `yaml:"basicAuthPassword"`
+	SecureJsonData    map[string]string `yaml:"secureJsonData"`
+}
+
+// datasourceSecrets are the secrets of the -secrets file keyed by datasource name or uid
+type datasourceSecrets map[string]*DatasourceSecrets
+
+// loadDatasourceSecrets reads the -secrets file and expands the ${VAR} environment variables of every secret, eg;
+//
+//	prometheus:
+//	  basicAuthPassword: ${PROMETHEUS_PASSWORD}
+//	  secureJsonData:
+//	    httpHeaderValue1: Bearer ${PROMETHEUS_TOKEN}
+func loadDatasourceSecrets(path string) (datasourceSecrets, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	defer f.Close()
+	secrets := datasourceSecrets{}
+	if err := yaml.NewDecoder(f).Decode(&secrets); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("yaml.Decode %s: %w", path, err)
+	}
+	for key, datasourceSecrets := range secrets {
+		if datasourceSecrets == nil {
+			continue
+		}
+		if datasourceSecrets.Password, err = expandSecret(datasourceSecrets.Password); err != nil {
+			return nil, fmt.Errorf("-secrets %s password: %w", key, err)
+		}
+		if datasourceSecrets.BasicAuthPassword, err = expandSecret(datasourceSecrets.BasicAuthPassword); err != nil {
+			return nil, fmt.Errorf("-secrets %s basicAuthPassword: %w", key, err)
+		}
+		for field, value := range datasourceSecrets.SecureJsonData {
+			if datasourceSecrets.SecureJsonData[field], err = expandSecret(value); err != nil {
+				return nil, fmt.Errorf("-secrets %s secureJsonData.%s: %w", key, field, err)
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// expandSecret replaces the ${VAR} references of a secret with the value of the environment variables, unset
+// variables are an error rather than an empty secret. Other $ are literal and $${ escapes a literal ${.
+func expandSecret(value string) (string, error) {
+	var expanded strings.Builder
+	for {
+		i := strings.Index(value, "${")
+		if i < 0 {
+			expanded.WriteString(value)
+			return expanded.String(), nil
+		}
+		if i > 0 && value[i-1] == '$' {
+			expanded.WriteString(value[:i-1] + "${")
+			value = value[i+2:]
+			continue
+		}
+		end := strings.IndexByte(value[i+2:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in secret, escape a literal ${ as $${")
+		}
+		name := value[i+2 : i+2+end]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		expanded.WriteString(value[:i] + v)
+		value = value[i+3+end:]
+	}
+}
+
+// apply sets the secrets of the datasource with the name or uid and reports whether it has any
+func (s datasourceSecrets) apply(datasource *grafsdk.Datasource) bool {
+	secrets := s[datasource.UID]
+	if secrets == nil {
+		secrets = s[datasource.Name]
+	}
+	if secrets == nil {
+		return false
+	}
+	if secrets.Password != "" {
+		datasource.Password = secrets.Password
+	}
+	if secrets.BasicAuthPassword != "" {
+		datasource.BasicAuthPassword = secrets.BasicAuthPassword
+	}
+	if len(secrets.SecureJsonData) > 0 {
+		datasource.SecureJsonData = map[string]string{}
+		for field, value := range secrets.SecureJsonData {
+			datasource.SecureJsonData[field] = value
+		}
+	}
+	return true
+}
+
+// hasSecrets reports whether the datasource had secrets when the backup was taken
+func hasSecrets(datasource *grafsdk.Datasource) bool {
+	for _, set := range datasource.SecureJsonFields {
+		if set {
+			return true
+		}
+	}
+	return false
+}
+
+// missingSecrets returns the sorted names of the datasources that had secrets when the backup was taken
+// without secrets in the -secrets file, they are restored without their passwords and tokens
+func (s datasourceSecrets) missingSecrets(datasources []*grafsdk.Datasource) []string {
+	names := []string{}
+	for _, datasource := range datasources {
+		if !hasSecrets(datasource) || s[datasource.UID] != nil || s[datasource.Name] != nil {
+			continue
+		}
+		names = append(names, datasource.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,100 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeSecrets(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadDatasourceSecrets(t *testing.T) {
+	t.Setenv("GRAFCTL_TEST_TOKEN", "s3cr3t")
+	secrets, err := loadDatasourceSecrets(writeSecrets(t, `
+prometheus:
+  basicAuthPassword: ${GRAFCTL_TEST_TOKEN}
+  secureJsonData:
+    httpHeaderValue1: Bearer ${GRAFCTL_TEST_TOKEN}
+postgres-uid:
+  password: pa$$w0rd
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", secrets["prometheus"].BasicAuthPassword)
+	assert.Equal(t, map[string]string{"httpHeaderValue1": "Bearer s3cr3t"}, secrets["prometheus"].SecureJsonData)
+	assert.Equal(t, "pa$$w0rd", secrets["postgres-uid"].Password)
+
+	_, err = loadDatasourceSecrets(writeSecrets(t, "prometheus:\n  password: ${GRAFCTL_TEST_UNSET}\n"))
+	assert.EqualError(t, err, "-secrets prometheus password: environment variable GRAFCTL_TEST_UNSET is not set")
+
+	datasources := []*grafsdk.Datasource{
+		{UID: "prometheus-uid", Name: "prometheus", SecureJsonFields: map[string]bool{"basicAuthPassword": true}},
+		{UID: "postgres-uid", Name: "postgres", SecureJsonFields: map[string]bool{"password": true}},
+		{UID: "loki-uid", Name: "loki", SecureJsonFields: map[string]bool{"httpHeaderValue1": true}},
+		{UID: "tempo-uid", Name: "tempo", SecureJsonFields: map[string]bool{}},
+	}
+	assert.Equal(t, []string{"loki"}, secrets.missingSecrets(datasources))
+	assert.Equal(t, []string{"loki", "postgres", "prometheus"}, datasourceSecrets(nil).missingSecrets(datasources))
+}
+
+func TestExpandSecret(t *testing.T) {
+	t.Setenv("GRAFCTL_TEST_TOKEN", "s3cr3t")
+	for value, expected := range map[string]string{
+		"${GRAFCTL_TEST_TOKEN}":         "s3cr3t",
+		"Bearer ${GRAFCTL_TEST_TOKEN}!": "Bearer s3cr3t!",
+		// bare $ are literal, only ${VAR} references are expanded
+		"pa$$w0rd":                    "pa$$w0rd",
+		"ab$cd":                       "ab$cd",
+		"$HOME":                       "$HOME",
+		"trailing$":                   "trailing$",
+		"$${GRAFCTL_TEST_TOKEN}":      "${GRAFCTL_TEST_TOKEN}",
+		"a$${b}${GRAFCTL_TEST_TOKEN}": "a${b}s3cr3t",
+	} {
+		expanded, err := expandSecret(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, expanded, value)
+	}
+
+	_, err := expandSecret("${GRAFCTL_TEST_UNSET}")
+	assert.EqualError(t, err, "environment variable GRAFCTL_TEST_UNSET is not set")
+	_, err = expandSecret("ab${cd")
+	assert.EqualError(t, err, "unterminated ${ in secret, escape a literal ${ as $${")
+}
+
+func TestImportSecrets(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	sourceOrg.addDatasource("prometheus", "prometheus").SecureJsonFields = map[string]bool{"basicAuthPassword": true}
+	sourceOrg.addDatasource("postgres", "postgres").SecureJsonFields = map[string]bool{"password": true}
+	src := backupFile(t, source, BackupOptions{})
+
+	target := newFakeGrafana(t)
+	targetOrg := target.orgs[1]
+	targetOrg.addDatasource("postgres", "postgres")
+	t.Setenv("GRAFCTL_TEST_TOKEN", "s3cr3t")
+	secrets := writeSecrets(t, `
+prometheus:
+  basicAuthPassword: ${GRAFCTL_TEST_TOKEN}
+  secureJsonData:
+    httpHeaderValue1: Bearer ${GRAFCTL_TEST_TOKEN}
+postgres-uid:
+  password: plain
+`)
+	assert.NoError(t, runImport(t, target, "-src", src, "-secrets", secrets))
+
+	assert.Equal(t, map[string]map[string]string{
+		"prometheus": {"basicAuthPassword": "s3cr3t", "httpHeaderValue1": "Bearer s3cr3t"},
+		"postgres":   {"password": "plain"},
+	}, targetOrg.datasourceSecrets)
+	for _, datasource := range targetOrg.datasources {
+		assert.Empty(t, datasource.Password)
+		assert.Empty(t, datasource.BasicAuthPassword)
+		assert.Nil(t, datasource.SecureJsonData)
+	}
+}
@@ -0,0 +1,361 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"google.golang.org/api/iterator"
+)
+
+// BackupObject describes a backup archive kept in a BackupStore
+type BackupObject struct {
+	Name    string
+	Size    int64
+	Updated time.Time
+}
+
+// BackupStore is the storage the backup archives are written to and read from.
+// Object names are relative to the location of the store, eg; the bucket prefix or the local dir.
+type BackupStore interface {
+	// Put returns a writer for the named object, the object is only created once Close succeeds.
+	// Cancelling ctx before calling Close aborts the write.
+	Put(ctx context.Context, name string) (io.WriteCloser, error)
+	// Get returns a reader for the named object
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the objects whose name starts with prefix sorted by name
+	List(ctx context.Context, prefix string) ([]*BackupObject, error)
+	// Delete removes the named object
+	Delete(ctx context.Context, name string) error
+}
+
+// S3Options has the settings used to reach an S3 compatible object storage
+type S3Options struct {
+	// Endpoint is the host of the S3 API, eg; s3.amazonaws.com or localhost:9000 for MinIO
+	Endpoint string
+	Region   string
+	// Insecure uses plain HTTP to reach the endpoint
+	Insecure bool
+}
+
+// parseBackupLocation splits a backup location into its provider and the location within the provider,
+// eg; s3://bucket/prefix yields the s3 provider and bucket/prefix.
+// Locations without a scheme use the given provider.
+func parseBackupLocation(provider BackupProvider, location string) (BackupProvider, string) {
+	switch {
+	case strings.HasPrefix(location, "gs://"):
+		return GCSBackupProvider, strings.TrimPrefix(location, "gs://")
+	case strings.HasPrefix(location, "s3://"):
+		return S3BackupProvider, strings.TrimPrefix(location, "s3://")
+	}
+	return provider, location
+}
+
+// splitBackupSrc splits the location of a backup archive into the location of its store and the object name
+func splitBackupSrc(src string) (BackupProvider, string, string) {
+	provider, location := parseBackupLocation(LocalBackupProvider, src)
+	if provider == LocalBackupProvider {
+		return provider, filepath.Dir(location), filepath.Base(location)
+	}
+	dir, name := path.Split(location)
+	return provider, strings.TrimSuffix(dir, "/"), name
+}
+
+// splitBucket splits a bucket location into the bucket name and the object prefix
+func splitBucket(location string) (string, string) {
+	bucket, prefix, _ := strings.Cut(strings.Trim(location, "/"), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return bucket, prefix
+}
+
+// NewBackupStore creates the store for the location of the given provider
+func NewBackupStore(ctx context.Context, provider BackupProvider, location string, s3Options S3Options) (BackupStore, error) {
+	switch provider {
+	case LocalBackupProvider:
+		return NewLocalBackupStore(location), nil
+	case GCSBackupProvider:
+		return NewGCSBackupStore(ctx, location)
+	case S3BackupProvider:
+		return NewS3BackupStore(ctx, location, s3Options)
+	default:
+		return nil, fmt.Errorf("provider %q not supported", provider)
+	}
+}
+
+// LocalBackupStore keeps the backup archives in a local dir
+type LocalBackupStore struct {
+	dir string
+}
+
+// NewLocalBackupStore creates a store for the local dir, an empty dir is the working directory
+func NewLocalBackupStore(dir string) *LocalBackupStore {
+	return &LocalBackupStore{dir: dir}
+}
+
+// localWriter writes to a temp file renamed to the object name on Close
+type localWriter struct {
+	ctx  context.Context
+	f    *os.File
+	path string
+}
+
+func (w *localWriter) Write(p []byte) (int, error) {
+	return w.f.Write(p)
+}
+
+func (w *localWriter) Close() error {
+	err := w.f.Close()
+	if err == nil {
+		err = w.ctx.Err()
+	}
+	if err == nil {
+		err = os.Rename(w.f.Name(), w.path)
+	}
+	if err != nil {
+		os.Remove(w.f.Name())
+	}
+	return err
+}
+
+// Put creates the named file
+func (s *LocalBackupStore) Put(ctx context.Context, name string) (io.WriteCloser, error) {
+	p := filepath.Join(s.dir, name)
+	if _, err := os.Stat(p); err == nil {
+		return nil, fmt.Errorf("%s already exists", p)
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &localWriter{ctx: ctx, f: f, path: p}, nil
+}
+
+// Get opens the named file
+func (s *LocalBackupStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("os.Open: %w", err)
+	}
+	return f, nil
+}
+
+// List returns the files of the dir whose name starts with prefix
+func (s *LocalBackupStore) List(ctx context.Context, prefix string) ([]*BackupObject, error) {
+	dir := s.dir
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadDir: %w", err)
+	}
+	objects := []*BackupObject{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, &BackupObject{Name: entry.Name(), Size: info.Size(), Updated: info.ModTime()})
+	}
+	return objects, nil
+}
+
+// Delete removes the named file
+func (s *LocalBackupStore) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// GCSBackupStore keeps the backup archives in a google cloud storage bucket
+type GCSBackupStore struct {
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+// NewGCSBackupStore creates a store for the bucket location, eg; grafana-backup-bucket/prefix
+func NewGCSBackupStore(ctx context.Context, location string) (*GCSBackupStore, error) {
+	bucketName, prefix := splitBucket(location)
+	if bucketName == "" {
+		return nil, fmt.Errorf("missing bucket location")
+	}
+	gcsClient, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage.NewClient: %w", err)
+	}
+	bucket := gcsClient.Bucket(bucketName)
+	if _, err := bucket.Attrs(ctx); err != nil {
+		return nil, fmt.Errorf("error getting bucket %q attributes: %w", bucketName, err)
+	}
+	return &GCSBackupStore{bucket: bucket, prefix: prefix}, nil
+}
+
+// Put uploads the named object, cancelling ctx aborts the upload
+func (s *GCSBackupStore) Put(ctx context.Context, name string) (io.WriteCloser, error) {
+	return s.bucket.Object(s.prefix + name).NewWriter(ctx), nil
+}
+
+// Get downloads the named object
+func (s *GCSBackupStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, err := s.bucket.Object(s.prefix + name).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Bucket.NewReader: %w", err)
+	}
+	return r, nil
+}
+
+// List returns the objects under the store prefix whose name starts with prefix
+func (s *GCSBackupStore) List(ctx context.Context, prefix string) ([]*BackupObject, error) {
+	objects := []*BackupObject{}
+	it := s.bucket.Objects(ctx, &storage.Query{Prefix: s.prefix + prefix, Delimiter: "/"})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Bucket.Objects: %w", err)
+		}
+		if attrs.Name == "" {
+			// sub prefixes are not backups
+			continue
+		}
+		objects = append(objects, &BackupObject{Name: strings.TrimPrefix(attrs.Name, s.prefix), Size: attrs.Size, Updated: attrs.Updated})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+// Delete removes the named object
+func (s *GCSBackupStore) Delete(ctx context.Context, name string) error {
+	return s.bucket.Object(s.prefix + name).Delete(ctx)
+}
+
+// S3BackupStore keeps the backup archives in an S3 compatible bucket, eg; AWS S3 or MinIO
+type S3BackupStore struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3BackupStore creates a store for the bucket location, eg; grafana-backup-bucket/prefix.
+// Credentials are read from the AWS_* env vars, the AWS credentials file or the instance IAM role.
+func NewS3BackupStore(ctx context.Context, location string, opts S3Options) (*S3BackupStore, error) {
+	bucketName, prefix := splitBucket(location)
+	if bucketName == "" {
+		return nil, fmt.Errorf("missing bucket location")
+	}
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		}),
+		Secure: !opts.Insecure,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("minio.New: %w", err)
+	}
+	exists, err := client.BucketExists(ctx, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bucket %q: %w", bucketName, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("bucket %q does not exist", bucketName)
+	}
+	return &S3BackupStore{client: client, bucket: bucketName, prefix: prefix}, nil
+}
+
+// s3Writer pipes the writes to a multipart upload running in the background
+type s3Writer struct {
+	ctx  context.Context
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *s3Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *s3Writer) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		w.pw.CloseWithError(err)
+	} else {
+		w.pw.Close()
+	}
+	return <-w.done
+}
+
+// Put uploads the named object, the upload is aborted when ctx is cancelled
+func (s *S3BackupStore) Put(ctx context.Context, name string) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	w := &s3Writer{ctx: ctx, pw: pw, done: make(chan error, 1)}
+	go func() {
+		_, err := s.client.PutObject(ctx, s.bucket, s.prefix+name, pr, -1, minio.PutObjectOptions{ContentType: "application/gzip"})
+		if err != nil {
+			err = fmt.Errorf("PutObject: %w", err)
+		}
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
+
+// Get downloads the named object
+func (s *S3BackupStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, s.prefix+name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("GetObject: %w", err)
+	}
+	// errors such as a missing object only surface once the object is read
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("GetObject: %w", err)
+	}
+	return object, nil
+}
+
+// List returns the objects under the store prefix whose name starts with prefix
+func (s *S3BackupStore) List(ctx context.Context, prefix string) ([]*BackupObject, error) {
+	objects := []*BackupObject{}
+	for info := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix + prefix}) {
+		if info.Err != nil {
+			return nil, fmt.Errorf("ListObjects: %w", info.Err)
+		}
+		if strings.HasSuffix(info.Key, "/") {
+			// sub prefixes are not backups
+			continue
+		}
+		objects = append(objects, &BackupObject{Name: strings.TrimPrefix(info.Key, s.prefix), Size: info.Size, Updated: info.LastModified})
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects, nil
+}
+
+// Delete removes the named object
+func (s *S3BackupStore) Delete(ctx context.Context, name string) error {
+	return s.client.RemoveObject(ctx, s.bucket, s.prefix+name, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBackupLocation(t *testing.T) {
+	provider, location := parseBackupLocation(LocalBackupProvider, "s3://bucket/prefix")
+	assert.Equal(t, S3BackupProvider, provider)
+	assert.Equal(t, "bucket/prefix", location)
+	provider, location = parseBackupLocation(LocalBackupProvider, "gs://bucket")
+	assert.Equal(t, GCSBackupProvider, provider)
+	assert.Equal(t, "bucket", location)
+	provider, location = parseBackupLocation(GCSBackupProvider, "bucket")
+	assert.Equal(t, GCSBackupProvider, provider)
+	assert.Equal(t, "bucket", location)
+
+	provider, location, name := splitBackupSrc("s3://bucket/prefix/grafana-2020-12-20-1.json.gz")
+	assert.Equal(t, S3BackupProvider, provider)
+	assert.Equal(t, "bucket/prefix", location)
+	assert.Equal(t, "grafana-2020-12-20-1.json.gz", name)
+	provider, location, name = splitBackupSrc("gs://bucket/grafana-2020-12-20-1.json.gz")
+	assert.Equal(t, GCSBackupProvider, provider)
+	assert.Equal(t, "bucket", location)
+	assert.Equal(t, "grafana-2020-12-20-1.json.gz", name)
+	provider, location, name = splitBackupSrc("./backups/grafana-2020-12-20-1.json.gz")
+	assert.Equal(t, LocalBackupProvider, provider)
+	assert.Equal(t, "backups", location)
+	assert.Equal(t, "grafana-2020-12-20-1.json.gz", name)
+
+	bucket, prefix := splitBucket("bucket/a/b/")
+	assert.Equal(t, "bucket", bucket)
+	assert.Equal(t, "a/b/", prefix)
+	bucket, prefix = splitBucket("bucket")
+	assert.Equal(t, "bucket", bucket)
+	assert.Equal(t, "", prefix)
+}
+
+// testBackupStore runs the BackupStore contract against a store
+func testBackupStore(t *testing.T, store BackupStore) {
+	ctx := context.Background()
+
+	w, err := store.Put(ctx, "grafana-1.json.gz")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "backup")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	// an aborted write does not create the object
+	abortCtx, cancel := context.WithCancel(ctx)
+	w, err = store.Put(abortCtx, "grafana-2.json.gz")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "partial")
+	assert.NoError(t, err)
+	cancel()
+	assert.Error(t, w.Close())
+
+	objects, err := store.List(ctx, "grafana-")
+	assert.NoError(t, err)
+	if assert.Len(t, objects, 1) {
+		assert.Equal(t, "grafana-1.json.gz", objects[0].Name)
+		assert.Equal(t, int64(len("backup")), objects[0].Size)
+		assert.False(t, objects[0].Updated.IsZero())
+	}
+	objects, err = store.List(ctx, "other-")
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+
+	r, err := store.Get(ctx, "grafana-1.json.gz")
+	assert.NoError(t, err)
+	by, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, "backup", string(by))
+
+	_, err = store.Get(ctx, "missing.json.gz")
+	assert.Error(t, err)
+
+	assert.NoError(t, store.Delete(ctx, "grafana-1.json.gz"))
+	objects, err = store.List(ctx, "grafana-")
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+}
+
+func TestLocalBackupStore(t *testing.T) {
+	dir := t.TempDir()
+	testBackupStore(t, NewLocalBackupStore(dir))
+
+	// no temp files are left behind
+	matches, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+// TestS3BackupStore runs against an existing bucket of an S3 compatible server, eg; a local MinIO:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	AWS_ACCESS_KEY_ID=minioadmin AWS_SECRET_ACCESS_KEY=minioadmin GRAFCTL_TEST_S3_ENDPOINT=localhost:9000 GRAFCTL_TEST_S3_BUCKET=grafctl go test ./...
+func TestS3BackupStore(t *testing.T) {
+	endpoint, bucket := os.Getenv("GRAFCTL_TEST_S3_ENDPOINT"), os.Getenv("GRAFCTL_TEST_S3_BUCKET")
+	if endpoint == "" || bucket == "" {
+		t.Skip("GRAFCTL_TEST_S3_ENDPOINT and GRAFCTL_TEST_S3_BUCKET are not set")
+	}
+	store, err := NewS3BackupStore(context.Background(), bucket+"/"+t.Name(), S3Options{Endpoint: endpoint, Insecure: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	testBackupStore(t, store)
+}
@@ -0,0 +1,157 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/diogogmt/grafctl/pkg/grafsdk"
+)
+
+// TeamBackup is a team with the logins of its members, the numeric ids differ between grafana instances
+type TeamBackup struct {
+	Name    string   `json:"name"`
+	Email   string   `json:"email,omitempty"`
+	Members []string `json:"members"`
+}
+
+func userKey(user *grafsdk.OrgUser) string {
+	return "user/" + user.Login
+}
+
+func teamKey(team *TeamBackup) string {
+	return "team/" + team.Name
+}
+
+// parseBackupIncludes parses the comma separated opt-in sections of a backup, eg; users,teams
+func parseBackupIncludes(include string) (bool, bool, error) {
+	users, teams := false, false
+	for _, section := range strings.Split(include, ",") {
+		switch strings.TrimSpace(section) {
+		case "":
+		case "users":
+			users = true
+		case "teams":
+			teams = true
+		default:
+			return false, false, fmt.Errorf("unknown -include %q, expected users or teams", section)
+		}
+	}
+	return users, teams, nil
+}
+
+// fetchTeams returns the teams of the client org with their members
+func (c *Client) fetchTeams(ctx context.Context) ([]*TeamBackup, error) {
+	teams, err := c.SearchTeams(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("SearchTeams: %w", err)
+	}
+	teamBackups := make([]*TeamBackup, 0, len(teams))
+	for _, team := range teams {
+		members, err := c.ListTeamMembers(ctx, team.ID)
+		if err != nil {
+			return nil, fmt.Errorf("ListTeamMembers %s: %w", team.Name, err)
+		}
+		teamBackup := TeamBackup{Name: team.Name, Email: team.Email, Members: []string{}}
+		for _, member := range members {
+			teamBackup.Members = append(teamBackup.Members, member.Login)
+		}
+		teamBackups = append(teamBackups, &teamBackup)
+	}
+	return teamBackups, nil
+}
+
+// importUsersAndTeams adds the existing grafana users to the org with their backup role, creates the missing teams
+// and adds their members. Users are never created since the backup has no passwords, the users missing from the
+// target grafana are reported instead.
+func (i *importer) importUsersAndTeams(ctx context.Context, users []*grafsdk.OrgUser, teams []*TeamBackup) error {
+	if len(users) == 0 && len(teams) == 0 {
+		return nil
+	}
+	client := i.client
+	missing := map[string]bool{}
+	added := 0
+
+	orgUsers, err := client.ListOrgUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("ListOrgUsers: %w", err)
+	}
+	orgUserIDs := map[string]int64{}
+	for _, orgUser := range orgUsers {
+		orgUserIDs[orgUser.Login] = orgUser.UserID
+	}
+	for _, user := range users {
+		if _, ok := orgUserIDs[user.Login]; ok {
+			continue
+		}
+		userID, err := client.AddOrgUser(ctx, user.Login, user.Role)
+		if grafsdk.IsNotFound(err) {
+			missing[user.Login] = true
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("AddOrgUser %s: %w", user.Login, err)
+		}
+		i.conf.logd("added user %s to the org as %s", user.Login, user.Role)
+		i.journal.notRolledBack("org user", user.Login)
+		orgUserIDs[user.Login] = userID
+		added++
+	}
+
+	existingTeams, err := client.SearchTeams(ctx)
+	if err != nil {
+		return fmt.Errorf("SearchTeams: %w", err)
+	}
+	teamIDs := map[string]int64{}
+	for _, team := range existingTeams {
+		teamIDs[team.Name] = team.ID
+	}
+	for _, team := range teams {
+		memberIDs := map[int64]bool{}
+		teamID, ok := teamIDs[team.Name]
+		if ok {
+			members, err := client.ListTeamMembers(ctx, teamID)
+			if err != nil {
+				return fmt.Errorf("ListTeamMembers %s: %w", team.Name, err)
+			}
+			for _, member := range members {
+				memberIDs[member.UserID] = true
+			}
+		} else {
+			i.conf.logd("team %s does not exist, creating new one", team.Name)
+			newTeam, err := client.CreateTeam(ctx, &grafsdk.Team{Name: team.Name, Email: team.Email})
+			if err != nil {
+				return fmt.Errorf("CreateTeam %s: %w", team.Name, err)
+			}
+			teamID = newTeam.ID
+			i.journal.notRolledBack("team", team.Name)
+		}
+		for _, login := range team.Members {
+			userID, ok := orgUserIDs[login]
+			if !ok {
+				missing[login] = true
+				continue
+			}
+			if memberIDs[userID] {
+				continue
+			}
+			if err := client.AddTeamMember(ctx, teamID, userID); err != nil {
+				return fmt.Errorf("AddTeamMember %s %s: %w", team.Name, login, err)
+			}
+			i.journal.notRolledBack("team member", team.Name+"/"+login)
+		}
+	}
+
+	if len(missing) > 0 {
+		logins := make([]string, 0, len(missing))
+		for login := range missing {
+			logins = append(logins, login)
+		}
+		sort.Strings(logins)
+		log.Printf("warning: %d user(s) do not exist in the target grafana, create them and import again: %s", len(logins), strings.Join(logins, ", "))
+	}
+	i.conf.logd("added %d user(s) to the org and imported %d team(s)", added, len(teams))
+	return nil
+}
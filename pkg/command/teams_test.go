@@ -0,0 +1,65 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBackupIncludes(t *testing.T) {
+	users, teams, err := parseBackupIncludes("")
+	assert.NoError(t, err)
+	assert.False(t, users)
+	assert.False(t, teams)
+
+	users, teams, err = parseBackupIncludes("users, teams")
+	assert.NoError(t, err)
+	assert.True(t, users)
+	assert.True(t, teams)
+
+	_, _, err = parseBackupIncludes("users,passwords")
+	assert.Error(t, err)
+}
+
+func TestImportUsersAndTeams(t *testing.T) {
+	source := newFakeGrafana(t)
+	sourceOrg := source.orgs[1]
+	alice := sourceOrg.addUser(20, "alice")
+	alice.Role = "Editor"
+	bob := sourceOrg.addUser(21, "bob")
+	sre := sourceOrg.addTeam(10, "sre")
+	sourceOrg.addTeamMember(sre, alice)
+	sourceOrg.addTeamMember(sre, bob)
+	sourceOrg.addTeamMember(sourceOrg.addTeam(11, "dev"), alice)
+
+	// users and teams are opt-in
+	dir := t.TempDir()
+	assert.NoError(t, source.client().BackupGrafana(context.Background(), BackupOptions{Store: NewLocalBackupStore(dir)}))
+	grafanaBackup := readLocalBackup(t, dir)
+	assert.Empty(t, grafanaBackup.Users)
+	assert.Empty(t, grafanaBackup.Teams)
+
+	src := backupFile(t, source, BackupOptions{IncludeUsers: true, IncludeTeams: true})
+
+	// alice exists in the target grafana but not in the org, bob does not exist and dev already exists
+	target := newFakeGrafana(t)
+	target.logins["alice"] = 7
+	targetOrg := target.orgs[1]
+	targetOrg.addTeam(3, "dev")
+	assert.NoError(t, runImport(t, target, "-src", src))
+
+	assert.Len(t, targetOrg.users, 1)
+	assert.Equal(t, "alice", targetOrg.users[0].Login)
+	assert.Equal(t, int64(7), targetOrg.users[0].UserID)
+	assert.Equal(t, "Editor", targetOrg.users[0].Role)
+	assert.Len(t, targetOrg.teams, 2)
+	assert.Equal(t, "sre", targetOrg.teams[1].Name)
+	assert.Equal(t, []int64{7}, targetOrg.teamMembers[targetOrg.teams[1].ID])
+	assert.Equal(t, []int64{7}, targetOrg.teamMembers[3])
+
+	// importing again does not add the members twice
+	assert.NoError(t, runImport(t, target, "-src", src))
+	assert.Len(t, targetOrg.teams, 2)
+	assert.Equal(t, []int64{7}, targetOrg.teamMembers[3])
+}
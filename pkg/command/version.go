@@ -0,0 +1,5 @@
+package command
+
+// Version is the grafctl version recorded in the backup manifests, set at build time with
+// -ldflags '-X github.com/diogogmt/grafctl/pkg/command.Version=v1.0.0'
+var Version = "dev"
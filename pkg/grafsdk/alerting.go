@@ -0,0 +1,217 @@
+package grafsdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/diogogmt/grafctl/pkg/simplejson"
+)
+
+// disableProvenanceHeader keeps the resources written through the provisioning API editable in the grafana UI
+const disableProvenanceHeader = "X-Disable-Provenance"
+
+// AlertRuleGroup is a group of alert rules of a folder evaluated at the same interval.
+// The rules are kept as raw JSON so fields added by newer grafana versions survive a backup.
+type AlertRuleGroup struct {
+	Title     string             `json:"title"`
+	FolderUID string             `json:"folderUid"`
+	Interval  int64              `json:"interval"`
+	Rules     []*simplejson.Json `json:"rules"`
+}
+
+type ContactPoint struct {
+	UID                   string           `json:"uid"`
+	Name                  string           `json:"name"`
+	Type                  string           `json:"type"`
+	Settings              *simplejson.Json `json:"settings"`
+	DisableResolveMessage bool             `json:"disableResolveMessage"`
+	Provenance            string           `json:"provenance,omitempty"`
+}
+
+type MuteTiming struct {
+	Name          string           `json:"name"`
+	TimeIntervals *simplejson.Json `json:"time_intervals"`
+	Version       string           `json:"version,omitempty"`
+	Provenance    string           `json:"provenance,omitempty"`
+}
+
+type NotificationTemplate struct {
+	Name       string `json:"name"`
+	Template   string `json:"template"`
+	Version    string `json:"version,omitempty"`
+	Provenance string `json:"provenance,omitempty"`
+}
+
+// provisioningRequest sends a request to the alerting provisioning API, writes disable the provenance
+func (c *Client) provisioningRequest(ctx context.Context, method string, path string, body interface{}, respData interface{}) error {
+	var bodyReader io.Reader
+	if body != nil {
+		by, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(by)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/api/v1/provisioning/%s", c.apiURL, path), bodyReader)
+	if err != nil {
+		return fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(disableProvenanceHeader, "true")
+	}
+	if _, _, err := c.do(ctx, req, respData); err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	return nil
+}
+
+// ListAlertRules lists the alert rules of every folder
+func (c *Client) ListAlertRules(ctx context.Context) ([]*simplejson.Json, error) {
+	rules := []*simplejson.Json{}
+	if err := c.provisioningRequest(ctx, http.MethodGet, "alert-rules", nil, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (c *Client) GetAlertRuleGroup(ctx context.Context, folderUID string, group string) (*AlertRuleGroup, error) {
+	ruleGroup := AlertRuleGroup{}
+	path := fmt.Sprintf("folder/%s/rule-groups/%s", url.PathEscape(folderUID), url.PathEscape(group))
+	if err := c.provisioningRequest(ctx, http.MethodGet, path, nil, &ruleGroup); err != nil {
+		return nil, err
+	}
+	return &ruleGroup, nil
+}
+
+// PutAlertRuleGroup creates or replaces the rule group, rules missing from the group are deleted
+func (c *Client) PutAlertRuleGroup(ctx context.Context, ruleGroup *AlertRuleGroup) error {
+	if ruleGroup == nil {
+		return fmt.Errorf("missing alert rule group")
+	}
+	path := fmt.Sprintf("folder/%s/rule-groups/%s", url.PathEscape(ruleGroup.FolderUID), url.PathEscape(ruleGroup.Title))
+	return c.provisioningRequest(ctx, http.MethodPut, path, ruleGroup, nil)
+}
+
+// ListContactPoints lists the contact points, secure settings are redacted by grafana
+func (c *Client) ListContactPoints(ctx context.Context) ([]*ContactPoint, error) {
+	contactPoints := []*ContactPoint{}
+	if err := c.provisioningRequest(ctx, http.MethodGet, "contact-points", nil, &contactPoints); err != nil {
+		return nil, err
+	}
+	return contactPoints, nil
+}
+
+// contactPointsExport is the export of the contact points, the contact points with the same name are grouped as the
+// receivers of a single contact point
+type contactPointsExport struct {
+	ContactPoints []struct {
+		Name      string `json:"name"`
+		Receivers []struct {
+			UID                   string           `json:"uid"`
+			Type                  string           `json:"type"`
+			Settings              *simplejson.Json `json:"settings"`
+			DisableResolveMessage bool             `json:"disableResolveMessage"`
+		} `json:"receivers"`
+	} `json:"contactPoints"`
+}
+
+// ExportContactPoints lists the contact points with their secure settings decrypted, it requires the permission to
+// read the alerting secrets and grafana 9.4 or newer
+func (c *Client) ExportContactPoints(ctx context.Context) ([]*ContactPoint, error) {
+	export := contactPointsExport{}
+	if err := c.provisioningRequest(ctx, http.MethodGet, "contact-points/export?decrypt=true&format=json", nil, &export); err != nil {
+		return nil, err
+	}
+	contactPoints := []*ContactPoint{}
+	for _, group := range export.ContactPoints {
+		for _, receiver := range group.Receivers {
+			contactPoints = append(contactPoints, &ContactPoint{
+				UID:                   receiver.UID,
+				Name:                  group.Name,
+				Type:                  receiver.Type,
+				Settings:              receiver.Settings,
+				DisableResolveMessage: receiver.DisableResolveMessage,
+			})
+		}
+	}
+	return contactPoints, nil
+}
+
+func (c *Client) CreateContactPoint(ctx context.Context, contactPoint *ContactPoint) error {
+	if contactPoint == nil {
+		return fmt.Errorf("missing contact point")
+	}
+	return c.provisioningRequest(ctx, http.MethodPost, "contact-points", contactPoint, nil)
+}
+
+func (c *Client) UpdateContactPoint(ctx context.Context, contactPoint *ContactPoint) error {
+	if contactPoint == nil {
+		return fmt.Errorf("missing contact point")
+	}
+	return c.provisioningRequest(ctx, http.MethodPut, "contact-points/"+url.PathEscape(contactPoint.UID), contactPoint, nil)
+}
+
+// GetNotificationPolicies returns the notification policy tree
+func (c *Client) GetNotificationPolicies(ctx context.Context) (*simplejson.Json, error) {
+	policies := simplejson.New()
+	if err := c.provisioningRequest(ctx, http.MethodGet, "policies", nil, policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// SetNotificationPolicies replaces the whole notification policy tree
+func (c *Client) SetNotificationPolicies(ctx context.Context, policies *simplejson.Json) error {
+	if policies == nil {
+		return fmt.Errorf("missing notification policies")
+	}
+	return c.provisioningRequest(ctx, http.MethodPut, "policies", policies, nil)
+}
+
+func (c *Client) ListMuteTimings(ctx context.Context) ([]*MuteTiming, error) {
+	muteTimings := []*MuteTiming{}
+	if err := c.provisioningRequest(ctx, http.MethodGet, "mute-timings", nil, &muteTimings); err != nil {
+		return nil, err
+	}
+	return muteTimings, nil
+}
+
+func (c *Client) CreateMuteTiming(ctx context.Context, muteTiming *MuteTiming) error {
+	if muteTiming == nil {
+		return fmt.Errorf("missing mute timing")
+	}
+	return c.provisioningRequest(ctx, http.MethodPost, "mute-timings", muteTiming, nil)
+}
+
+func (c *Client) UpdateMuteTiming(ctx context.Context, muteTiming *MuteTiming) error {
+	if muteTiming == nil {
+		return fmt.Errorf("missing mute timing")
+	}
+	return c.provisioningRequest(ctx, http.MethodPut, "mute-timings/"+url.PathEscape(muteTiming.Name), muteTiming, nil)
+}
+
+func (c *Client) ListNotificationTemplates(ctx context.Context) ([]*NotificationTemplate, error) {
+	templates := []*NotificationTemplate{}
+	if err := c.provisioningRequest(ctx, http.MethodGet, "templates", nil, &templates); err != nil {
+		return nil, err
+	}
+	// grafana answers null when there are no templates
+	if templates == nil {
+		templates = []*NotificationTemplate{}
+	}
+	return templates, nil
+}
+
+// PutNotificationTemplate creates or replaces the template with the same name
+func (c *Client) PutNotificationTemplate(ctx context.Context, template *NotificationTemplate) error {
+	if template == nil {
+		return fmt.Errorf("missing notification template")
+	}
+	return c.provisioningRequest(ctx, http.MethodPut, "templates/"+url.PathEscape(template.Name), template, nil)
+}
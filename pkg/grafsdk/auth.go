@@ -0,0 +1,70 @@
+package grafsdk
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Authenticator adds credentials to the requests sent to grafana
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface
+type AuthenticatorFunc func(req *http.Request) error
+
+// Authenticate calls f(req)
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// BearerAuth authenticates with an API key or service account token
+func BearerAuth(token string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		return nil
+	})
+}
+
+// BasicAuth authenticates with a grafana user and password
+func BasicAuth(user string, password string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.SetBasicAuth(user, password)
+		return nil
+	})
+}
+
+// HeaderAuth sets arbitrary headers, eg; the user header expected by an auth proxy
+func HeaderAuth(headers map[string]string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return nil
+	})
+}
+
+// CookieAuth sends session cookies, eg; grafana_session
+func CookieAuth(cookies ...*http.Cookie) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		return nil
+	})
+}
+
+// MultiAuth applies all the authenticators in order
+func MultiAuth(auths ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		for _, auth := range auths {
+			if auth == nil {
+				continue
+			}
+			if err := auth.Authenticate(req); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,48 @@
+package grafsdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticators(t *testing.T) {
+	var lastReq *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastReq = r
+		fmt.Fprint(w, `[]`)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	// bearer api key by default
+	_, err := New(srv.URL, "test-key").ListFolders(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer test-key", lastReq.Header.Get("Authorization"))
+
+	// basic auth
+	_, err = New(srv.URL, "", WithAuthenticator(BasicAuth("admin", "secret"))).ListFolders(ctx)
+	assert.NoError(t, err)
+	user, password, ok := lastReq.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "admin", user)
+	assert.Equal(t, "secret", password)
+
+	// cookie auth combined with auth proxy headers
+	auth := MultiAuth(
+		CookieAuth(&http.Cookie{Name: "grafana_session", Value: "abc"}),
+		HeaderAuth(map[string]string{"X-WEBAUTH-USER": "admin"}),
+	)
+	_, err = New(srv.URL, "test-key", WithAuthenticator(auth)).ListFolders(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "", lastReq.Header.Get("Authorization"))
+	assert.Equal(t, "admin", lastReq.Header.Get("X-WEBAUTH-USER"))
+	cookie, err := lastReq.Cookie("grafana_session")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", cookie.Value)
+}
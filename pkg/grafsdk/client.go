@@ -3,6 +3,7 @@ package grafsdk
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,11 +11,13 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Client struct {
 	apiURL     string
 	apiKey     string
+	orgID      int64
 	httpClient *HTTPClient
 }
 
@@ -38,6 +41,51 @@ func QuerySearchOption(q string) SearchOption {
 	}
 }
 
+// defaultSearchLimit is the page size used by SearchAll, grafana caps a single search at 5000 hits
+const defaultSearchLimit = 1000
+
+func LimitSearchOption(limit int) SearchOption {
+	return func(values *url.Values) {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+}
+
+func PageSearchOption(page int) SearchOption {
+	return func(values *url.Values) {
+		values.Set("page", strconv.Itoa(page))
+	}
+}
+
+func TagSearchOption(tags ...string) SearchOption {
+	return func(values *url.Values) {
+		for _, tag := range tags {
+			values.Add("tag", tag)
+		}
+	}
+}
+
+func StarredSearchOption() SearchOption {
+	return func(values *url.Values) {
+		values.Set("starred", "true")
+	}
+}
+
+func DashboardUIDsSearchOption(uids []string) SearchOption {
+	return func(values *url.Values) {
+		for _, uid := range uids {
+			values.Add("dashboardUIDs", uid)
+		}
+	}
+}
+
+func FolderUIDsSearchOption(uids []string) SearchOption {
+	return func(values *url.Values) {
+		for _, uid := range uids {
+			values.Add("folderUIDs", uid)
+		}
+	}
+}
+
 func FolderIDsSearchOption(ids []int64) SearchOption {
 	return func(values *url.Values) {
 		idsStr := make([]string, 0, len(ids))
@@ -48,18 +96,63 @@ func FolderIDsSearchOption(ids []int64) SearchOption {
 	}
 }
 
-func New(apiURL string, apiKey string) *Client {
+// Option configures optional behaviour of a Client
+type Option func(c *Client)
+
+// WithRetry retries failed requests up to retryMax times with an exponential backoff between waitMin and waitMax
+func WithRetry(retryMax int, waitMin time.Duration, waitMax time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.SetRetry(retryMax, waitMin, waitMax)
+	}
+}
+
+// WithRateLimit limits the client to rps requests per second
+func WithRateLimit(rps float64) Option {
+	return func(c *Client) {
+		c.httpClient.SetRateLimit(rps)
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to grafana, see NewTLSConfig
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *Client) {
+		c.httpClient.SetTLSConfig(tlsConfig)
+	}
+}
+
+// WithTimeout sets the timeout of every request attempt
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.SetTimeout(timeout)
+	}
+}
+
+// WithAuthenticator replaces the default bearer authentication with the API key
+func WithAuthenticator(auth Authenticator) Option {
+	return func(c *Client) {
+		c.httpClient.SetAuthenticator(auth)
+	}
+}
+
+// New creates a grafana client authenticating with the bearer apiKey unless WithAuthenticator is given
+func New(apiURL string, apiKey string, opts ...Option) *Client {
 	httpc := NewHTTPClient(context.Background())
 	httpc.SetHeaders(map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", apiKey),
-		"Accept":        "application/json",
-		"Content-Type":  "application/json",
+		"Accept":       "application/json",
+		"Content-Type": "application/json",
 	})
-	return &Client{
+	if apiKey != "" {
+		httpc.SetAuthenticator(BearerAuth(apiKey))
+	}
+	c := &Client{
 		apiURL:     apiURL,
 		apiKey:     apiKey,
 		httpClient: httpc,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 func (c *Client) SaveDashboard(ctx context.Context, payload *DashboardSavePayload) error {
@@ -71,15 +164,10 @@ func (c *Client) SaveDashboard(ctx context.Context, payload *DashboardSavePayloa
 	if err != nil {
 		return fmt.Errorf("NewRequestWithContext: %w", err)
 	}
-	resp, _, err := c.do(ctx, req, nil)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, nil); err != nil {
 		return fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("status code: %d", resp.StatusCode)
-	}
-
 	return nil
 }
 
@@ -89,21 +177,33 @@ func (c *Client) GetDashboardByUID(ctx context.Context, uid string) (*DashboardW
 		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
 	}
 	dashboard := &DashboardWithMeta{}
-	resp, _, err := c.do(ctx, req, dashboard)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, dashboard); err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	return dashboard, nil
+}
+
+// DeleteDashboardByUID deletes the dashboard with the uid
+func (c *Client) DeleteDashboardByUID(ctx context.Context, uid string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/api/dashboards/uid/%s", c.apiURL, uid), nil)
+	if err != nil {
+		return fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	if _, _, err := c.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("do: %w", err)
 	}
 
-	return dashboard, nil
+	return nil
 }
 
-func (c *Client) CreateFolder(ctx context.Context, title string) (*Folder, error) {
-	folder := Folder{Title: title}
-	folderBy, err := json.Marshal(folder)
+// CreateFolder creates the folder with the title, the uid is generated by grafana when empty and the parent uid
+// creates a subfolder on grafana versions with nested folders
+func (c *Client) CreateFolder(ctx context.Context, folder *Folder) (*Folder, error) {
+	if folder == nil {
+		return nil, fmt.Errorf("missing folder")
+	}
+	folderBy, err := json.Marshal(map[string]string{"uid": folder.UID, "title": folder.Title, "parentUid": folder.ParentUID})
 	if err != nil {
 		return nil, err
 	}
@@ -111,16 +211,28 @@ func (c *Client) CreateFolder(ctx context.Context, title string) (*Folder, error
 	if err != nil {
 		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
 	}
-	resp, _, err := c.do(ctx, req, &folder)
-	if err != nil {
+	created := Folder{}
+	if _, _, err := c.do(ctx, req, &created); err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
+	if created.ParentUID == "" {
+		created.ParentUID = folder.ParentUID
+	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	return &created, nil
+}
+
+// DeleteFolder deletes the folder with the uid and the dashboards it contains
+func (c *Client) DeleteFolder(ctx context.Context, uid string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/api/folders/%s", c.apiURL, url.PathEscape(uid)), nil)
+	if err != nil {
+		return fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	if _, _, err := c.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("do: %w", err)
 	}
 
-	return &folder, nil
+	return nil
 }
 
 func (c *Client) ListFolders(ctx context.Context) ([]*Folder, error) {
@@ -129,13 +241,23 @@ func (c *Client) ListFolders(ctx context.Context) ([]*Folder, error) {
 		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
 	}
 	folders := []*Folder{}
-	resp, _, err := c.do(ctx, req, &folders)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, &folders); err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	return folders, nil
+}
+
+// ListSubfolders lists the folders with the parent uid, grafana versions without nested folders ignore the parent uid
+// and list the top-level folders
+func (c *Client) ListSubfolders(ctx context.Context, parentUID string) ([]*Folder, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/folders?parentUid=%s", c.apiURL, url.QueryEscape(parentUID)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	folders := []*Folder{}
+	if _, _, err := c.do(ctx, req, &folders); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
 	}
 
 	return folders, nil
@@ -155,15 +277,10 @@ func (c *Client) CreateDatasource(ctx context.Context, datasource *Datasource) (
 	}
 
 	datasourceResp := Datasource{}
-	resp, _, err := c.do(ctx, req, &datasourceResp)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, &datasourceResp); err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
-	}
-
 	return &datasourceResp, nil
 }
 
@@ -179,13 +296,20 @@ func (c *Client) UpdateDatasource(ctx context.Context, datasource *Datasource) e
 	if err != nil {
 		return fmt.Errorf("NewRequestWithContext: %w", err)
 	}
-	resp, _, err := c.do(ctx, req, nil)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, nil); err != nil {
 		return fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("status code: %d", resp.StatusCode)
+	return nil
+}
+
+func (c *Client) DeleteDatasourceByName(ctx context.Context, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/api/datasources/name/%s", c.apiURL, url.PathEscape(name)), nil)
+	if err != nil {
+		return fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	if _, _, err := c.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("do: %w", err)
 	}
 
 	return nil
@@ -197,33 +321,23 @@ func (c *Client) GetDatasourceByID(ctx context.Context, id int64) (*Datasource,
 		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
 	}
 	datasource := Datasource{}
-	resp, _, err := c.do(ctx, req, &datasource)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, &datasource); err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
-	}
-
 	return &datasource, nil
 }
 
 func (c *Client) GetDatasourceByName(ctx context.Context, name string) (*Datasource, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/datasources/name/%s", c.apiURL, name), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/datasources/name/%s", c.apiURL, url.PathEscape(name)), nil)
 	if err != nil {
 		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
 	}
 	datasource := Datasource{}
-	resp, _, err := c.do(ctx, req, &datasource)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, &datasource); err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
-	}
-
 	return &datasource, nil
 }
 
@@ -233,15 +347,10 @@ func (c *Client) ListDatasources(ctx context.Context) ([]*Datasource, error) {
 		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
 	}
 	datasources := []*Datasource{}
-	resp, _, err := c.do(ctx, req, &datasources)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, &datasources); err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
-	}
-
 	return datasources, nil
 }
 
@@ -261,16 +370,48 @@ func (c *Client) Search(ctx context.Context, searchOptions ...SearchOption) ([]*
 		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
 	}
 	searchResults := []*SearchResult{}
-	resp, _, err := c.do(ctx, req, &searchResults)
-	if err != nil {
+	if _, _, err := c.do(ctx, req, &searchResults); err != nil {
 		return nil, fmt.Errorf("do: %w", err)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	return searchResults, nil
+}
+
+// SearchAll walks every page of the search results until they are exhausted,
+// the page size defaults to 1000 and can be changed with LimitSearchOption
+func (c *Client) SearchAll(ctx context.Context, searchOptions ...SearchOption) ([]*SearchResult, error) {
+	urlValues := url.Values{}
+	for _, searchOption := range searchOptions {
+		searchOption(&urlValues)
+	}
+	limit, err := strconv.Atoi(urlValues.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultSearchLimit
 	}
 
-	return searchResults, nil
+	searchResults := []*SearchResult{}
+	seen := map[string]bool{}
+	for page := 1; ; page++ {
+		pageOptions := append(searchOptions[:len(searchOptions):len(searchOptions)], LimitSearchOption(limit), PageSearchOption(page))
+		pageResults, err := c.Search(ctx, pageOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("Search page %d: %w", page, err)
+		}
+		added := 0
+		for _, searchResult := range pageResults {
+			key := fmt.Sprintf("%s/%s/%d", searchResult.Type, searchResult.UID, searchResult.ID)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			searchResults = append(searchResults, searchResult)
+			added++
+		}
+		// stop on the last page, or when an older grafana ignoring the page parameter repeats the results
+		if len(pageResults) < limit || added == 0 {
+			return searchResults, nil
+		}
+	}
 }
 
 func (a *Client) do(ctx context.Context, req *http.Request, respData interface{}) (*http.Response, []byte, error) {
@@ -286,6 +427,10 @@ func (a *Client) do(ctx context.Context, req *http.Request, respData interface{}
 		return resp, nil, fmt.Errorf("io.ReadAll: %w", err)
 	}
 
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, body, newAPIError(req, resp, body)
+	}
+
 	if respData != nil {
 		if err := json.Unmarshal(body, respData); err != nil {
 			return resp, nil, fmt.Errorf("json.Unmarshal: %w", err)
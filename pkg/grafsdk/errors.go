@@ -0,0 +1,85 @@
+package grafsdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is returned by Client methods when grafana answers with a non-2xx status code
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Message is the `message` field of the grafana error payload, if any
+	Message string
+	// Status is the `status` field of the grafana error payload, eg; version-mismatch, name-exists, not-found
+	Status string
+	// Method and URL identify the request that failed
+	Method string
+	URL    string
+	// Body is the raw response body
+	Body []byte
+}
+
+func newAPIError(req *http.Request, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Body:       body,
+	}
+	payload := struct {
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	}{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		apiErr.Message = payload.Message
+		apiErr.Status = payload.Status
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = strings.TrimSpace(string(e.Body))
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: status code: %d: %s", e.Method, e.URL, e.StatusCode, msg)
+}
+
+// IsNotFound reports whether err is an APIError for a missing resource
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound || apiErr.Status == "not-found"
+}
+
+// IsConflict reports whether err is an APIError caused by a conflicting resource,
+// eg; a dashboard version mismatch or a name that already exists
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Status {
+	case "version-mismatch", "name-exists", "plugin-dashboard":
+		return true
+	}
+	return apiErr.StatusCode == http.StatusConflict || apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// IsUnauthorized reports whether err is an APIError caused by missing or insufficient credentials
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,63 @@
+package grafsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/datasources/name/missing":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Data source not found"}`)
+		case "/api/dashboards/db":
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `{"message":"The dashboard has been changed by someone else","status":"version-mismatch"}`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, "invalid API key")
+		}
+	}))
+	defer srv.Close()
+
+	client := New(srv.URL, "test-key")
+	ctx := context.Background()
+
+	// not found
+	_, err := client.GetDatasourceByName(ctx, "missing")
+	assert.Error(t, err)
+	assert.True(t, IsNotFound(err))
+	assert.False(t, IsUnauthorized(err))
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "Data source not found", apiErr.Message)
+	assert.Equal(t, http.MethodGet, apiErr.Method)
+	assert.Equal(t, srv.URL+"/api/datasources/name/missing", apiErr.URL)
+
+	// conflict
+	err = client.SaveDashboard(ctx, &DashboardSavePayload{})
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsNotFound(err))
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "version-mismatch", apiErr.Status)
+
+	// unauthorized, non json body
+	_, err = client.ListDatasources(ctx)
+	assert.True(t, IsUnauthorized(err))
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "", apiErr.Message)
+	assert.Equal(t, []byte("invalid API key"), apiErr.Body)
+	assert.Contains(t, err.Error(), "invalid API key")
+
+	// non api errors
+	assert.False(t, IsNotFound(errors.New("boom")))
+	assert.False(t, IsConflict(nil))
+}
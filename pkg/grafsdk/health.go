@@ -0,0 +1,28 @@
+package grafsdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Health is the build info of a grafana server
+type Health struct {
+	Commit   string `json:"commit"`
+	Database string `json:"database"`
+	Version  string `json:"version"`
+}
+
+// GetHealth returns the health and build info of the grafana server, the endpoint does not require authentication
+func (c *Client) GetHealth(ctx context.Context) (*Health, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/health", c.apiURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	health := Health{}
+	if _, _, err := c.do(ctx, req, &health); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return &health, nil
+}
@@ -3,13 +3,23 @@ package grafsdk
 import (
 	"context"
 	"crypto/tls"
+	"io"
+	"math/rand"
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
+const (
+	defaultTimeout      = 120 * time.Second
+	defaultRetryWaitMin = 1 * time.Second
+	defaultRetryWaitMax = 30 * time.Second
+)
+
 var (
-	defaultNonVerifyingTransport http.RoundTripper = &http.Transport{
+	defaultTransport = &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
@@ -20,36 +30,76 @@ var (
 		MaxIdleConnsPerHost:   5,
 		IdleConnTimeout:       5 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-	}
-	defaultClient = &http.Client{
-		Timeout:   120 * time.Second,
-		Transport: defaultNonVerifyingTransport,
 	}
 )
 
 type HTTPClient struct {
 	client  *http.Client
 	headers map[string]string
+	auth    Authenticator
+
+	retryMax     int
+	retryWaitMin time.Duration
+	retryWaitMax time.Duration
+	limiter      *rateLimiter
 }
 
 func NewHTTPClient(ctx context.Context) *HTTPClient {
 	return &HTTPClient{
-		client:  defaultClient,
-		headers: make(map[string]string),
+		client: &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: defaultTransport,
+		},
+		headers:      make(map[string]string),
+		retryWaitMin: defaultRetryWaitMin,
+		retryWaitMax: defaultRetryWaitMax,
 	}
 }
 
+// Do sends the request, retrying failed attempts according to the retry policy
+// and waiting on the rate limiter before every attempt
 func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
 	for k, v := range c.headers {
 		req.Header.Set(k, v)
 	}
+	if c.auth != nil {
+		if err := c.auth.Authenticate(req); err != nil {
+			return nil, err
+		}
+	}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		if attempt > 0 && req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.client.Do(req)
+		if attempt >= c.retryMax || !c.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
 	}
-	return resp, nil
 }
 
 func (c *HTTPClient) SetHeaders(headers map[string]string) {
@@ -57,3 +107,159 @@ func (c *HTTPClient) SetHeaders(headers map[string]string) {
 		c.headers[k] = v
 	}
 }
+
+// DelHeader removes a header set with SetHeaders
+func (c *HTTPClient) DelHeader(key string) {
+	delete(c.headers, key)
+}
+
+// clone returns a copy with its own headers sharing the underlying http.Client and rate limiter
+func (c *HTTPClient) clone() *HTTPClient {
+	clone := *c
+	clone.headers = make(map[string]string, len(c.headers))
+	for k, v := range c.headers {
+		clone.headers[k] = v
+	}
+	return &clone
+}
+
+// SetAuthenticator sets the credentials added to every request
+func (c *HTTPClient) SetAuthenticator(auth Authenticator) {
+	c.auth = auth
+}
+
+// SetRetry configures how many times a failed request is retried and the bounds of the backoff between attempts
+func (c *HTTPClient) SetRetry(retryMax int, waitMin time.Duration, waitMax time.Duration) {
+	c.retryMax = retryMax
+	if waitMin > 0 {
+		c.retryWaitMin = waitMin
+	}
+	if waitMax > 0 {
+		c.retryWaitMax = waitMax
+	}
+	if c.retryWaitMax < c.retryWaitMin {
+		c.retryWaitMax = c.retryWaitMin
+	}
+}
+
+// SetRateLimit limits the client to rps requests per second, a value <= 0 disables the limiter
+func (c *HTTPClient) SetRateLimit(rps float64) {
+	if rps <= 0 {
+		c.limiter = nil
+		return
+	}
+	c.limiter = &rateLimiter{interval: time.Duration(float64(time.Second) / rps)}
+}
+
+// SetTLSConfig replaces the TLS configuration used to connect to grafana
+func (c *HTTPClient) SetTLSConfig(tlsConfig *tls.Config) {
+	transport := defaultTransport.Clone()
+	transport.TLSClientConfig = tlsConfig
+	c.client.Transport = transport
+}
+
+// SetTimeout sets the timeout of a single attempt, including reading the response body
+func (c *HTTPClient) SetTimeout(timeout time.Duration) {
+	c.client.Timeout = timeout
+}
+
+// shouldRetry reports whether an attempt can be retried.
+// Throttled requests are always retried since grafana did not process them,
+// transport errors and server errors are only retried for idempotent requests.
+func (c *HTTPClient) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if !isIdempotent(req.Method) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// backoff returns how long to wait before the next attempt, honouring the Retry-After header when present.
+// Retry-After is capped at the maximum wait so a proxy asking for hours doesn't stall the client.
+func (c *HTTPClient) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if wait > c.retryWaitMax {
+				wait = c.retryWaitMax
+			}
+			return wait
+		}
+	}
+	wait := c.retryWaitMin << uint(attempt)
+	if wait <= 0 || wait > c.retryWaitMax {
+		wait = c.retryWaitMax
+	}
+	// equal jitter, wait somewhere between half and the full backoff
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		wait := time.Until(t)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// rateLimiter spaces out requests so that at most one request is sent every interval
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// Wait blocks until the next request is allowed to be sent, a nil limiter never blocks
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
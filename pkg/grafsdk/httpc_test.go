@@ -0,0 +1,110 @@
+package grafsdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClientRetry(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&attempts, 1)
+		switch r.URL.Path {
+		case "/api/datasources":
+			// fail the first two attempts like a flaky load balancer
+			if n <= 2 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			fmt.Fprint(w, `[{"id":1,"name":"prometheus"}]`)
+		case "/api/dashboards/db":
+			body, _ := io.ReadAll(r.Body)
+			assert.JSONEq(t, `{"dashboard":null,"overwrite":true,"folderId":0,"folderUid":""}`, string(body))
+			if n == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	client := New(srv.URL, "test-key", WithRetry(3, time.Millisecond, 5*time.Millisecond))
+	ctx := context.Background()
+
+	// idempotent requests are retried on 5xx
+	datasources, err := client.ListDatasources(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, datasources, 1)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// non idempotent requests are retried on 429 with the body replayed
+	atomic.StoreInt32(&attempts, 0)
+	err = client.SaveDashboard(ctx, &DashboardSavePayload{Overwrite: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	// non idempotent requests are not retried on 5xx
+	atomic.StoreInt32(&attempts, 0)
+	_, err = client.CreateFolder(ctx, &Folder{Title: "folder"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	// retries give up after retryMax attempts
+	atomic.StoreInt32(&attempts, 0)
+	_, err = client.ListFolders(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&attempts))
+}
+
+func TestHTTPClientBackoff(t *testing.T) {
+	c := NewHTTPClient(context.Background())
+	c.SetRetry(5, 100*time.Millisecond, time.Second)
+
+	for attempt := 0; attempt < 10; attempt++ {
+		wait := c.backoff(attempt, nil)
+		assert.True(t, wait >= 50*time.Millisecond, "attempt %d waited %s", attempt, wait)
+		assert.True(t, wait <= time.Second, "attempt %d waited %s", attempt, wait)
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	c.SetRetry(5, 100*time.Millisecond, 10*time.Second)
+	resp.Header.Set("Retry-After", "7")
+	assert.Equal(t, 7*time.Second, c.backoff(0, resp))
+
+	// Retry-After is capped at the maximum wait
+	resp.Header.Set("Retry-After", "3600")
+	assert.Equal(t, 10*time.Second, c.backoff(0, resp))
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.Equal(t, 10*time.Second, c.backoff(0, resp))
+
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.Equal(t, time.Duration(0), c.backoff(0, resp))
+}
+
+func TestHTTPClientRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	defer srv.Close()
+
+	client := New(srv.URL, "test-key", WithRateLimit(50))
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		_, err := client.ListFolders(context.Background())
+		assert.NoError(t, err)
+	}
+	// the first request goes out immediately, the next five wait 20ms each
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+}
@@ -0,0 +1,129 @@
+package grafsdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/diogogmt/grafctl/pkg/simplejson"
+)
+
+// LibraryPanelKind is the kind of the library elements shared as panels between dashboards
+const LibraryPanelKind = 1
+
+// LibraryElement is a library panel or variable, dashboards reference it by uid
+type LibraryElement struct {
+	ID          int64            `json:"id,omitempty"`
+	OrgID       int64            `json:"orgId,omitempty"`
+	FolderID    int64            `json:"folderId"`
+	FolderUID   string           `json:"folderUid"`
+	UID         string           `json:"uid"`
+	Name        string           `json:"name"`
+	Kind        int64            `json:"kind"`
+	Type        string           `json:"type,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Model       *simplejson.Json `json:"model"`
+	Version     int64            `json:"version"`
+	Meta        *simplejson.Json `json:"meta,omitempty"`
+}
+
+// ListLibraryElements walks every page of the library elements
+func (c *Client) ListLibraryElements(ctx context.Context) ([]*LibraryElement, error) {
+	elements := []*LibraryElement{}
+	perPage := 100
+	for page := 1; ; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/library-elements?perPage=%d&page=%d", c.apiURL, perPage, page), nil)
+		if err != nil {
+			return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+		}
+		resp := struct {
+			Result struct {
+				TotalCount int               `json:"totalCount"`
+				Elements   []*LibraryElement `json:"elements"`
+			} `json:"result"`
+		}{}
+		if _, _, err := c.do(ctx, req, &resp); err != nil {
+			return nil, fmt.Errorf("do: %w", err)
+		}
+		elements = append(elements, resp.Result.Elements...)
+		if len(resp.Result.Elements) < perPage || len(elements) >= resp.Result.TotalCount {
+			return elements, nil
+		}
+	}
+}
+
+func (c *Client) GetLibraryElementByUID(ctx context.Context, uid string) (*LibraryElement, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/library-elements/%s", c.apiURL, url.PathEscape(uid)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	resp := struct {
+		Result *LibraryElement `json:"result"`
+	}{}
+	if _, _, err := c.do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return resp.Result, nil
+}
+
+func (c *Client) CreateLibraryElement(ctx context.Context, element *LibraryElement) (*LibraryElement, error) {
+	if element == nil {
+		return nil, fmt.Errorf("missing library element")
+	}
+	by, err := json.Marshal(element)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/library-elements", c.apiURL), bytes.NewReader(by))
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	resp := struct {
+		Result *LibraryElement `json:"result"`
+	}{}
+	if _, _, err := c.do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return resp.Result, nil
+}
+
+// UpdateLibraryElement replaces the library element with the same uid, the version must match the current version
+func (c *Client) UpdateLibraryElement(ctx context.Context, element *LibraryElement) (*LibraryElement, error) {
+	if element == nil {
+		return nil, fmt.Errorf("missing library element")
+	}
+	by, err := json.Marshal(element)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, fmt.Sprintf("%s/api/library-elements/%s", c.apiURL, url.PathEscape(element.UID)), bytes.NewReader(by))
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	resp := struct {
+		Result *LibraryElement `json:"result"`
+	}{}
+	if _, _, err := c.do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return resp.Result, nil
+}
+
+// DeleteLibraryElementByUID deletes the library element, grafana refuses to delete elements connected to dashboards
+func (c *Client) DeleteLibraryElementByUID(ctx context.Context, uid string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/api/library-elements/%s", c.apiURL, url.PathEscape(uid)), nil)
+	if err != nil {
+		return fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	if _, _, err := c.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package grafsdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListLibraryElements(t *testing.T) {
+	total := 250
+	requests := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("perPage"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		elements := []*LibraryElement{}
+		for i := (page - 1) * perPage; i < page*perPage && i < total; i++ {
+			elements = append(elements, &LibraryElement{UID: fmt.Sprintf("panel-%d", i), Kind: LibraryPanelKind})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result": map[string]interface{}{"totalCount": total, "page": page, "perPage": perPage, "elements": elements},
+		})
+	}))
+	defer srv.Close()
+
+	client := New(srv.URL, "test-key")
+	elements, err := client.ListLibraryElements(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, elements, total)
+	assert.Equal(t, "panel-249", elements[total-1].UID)
+	assert.Equal(t, []string{"perPage=100&page=1", "perPage=100&page=2", "perPage=100&page=3"}, requests)
+
+	// the last page is full, the total count avoids requesting an empty page
+	requests = nil
+	total = 200
+	elements, err = client.ListLibraryElements(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, elements, total)
+	assert.Len(t, requests, 2)
+}
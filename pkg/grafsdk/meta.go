@@ -43,38 +43,40 @@ type SearchResult struct {
 }
 
 type Folder struct {
-	ID       int64  `json:"id"`
-	UID      string `json:"uid"`
-	Title    string `json:"title"`
-	Url      string `json:"url"`
-	HasACL   bool   `json:"hasAcl"`
-	CanSave  bool   `json:"canSave"`
-	CanEdit  bool   `json:"canEdit"`
-	CanAdmin bool   `json:"canAdmin"`
-	Version  int    `json:"version"`
+	ID        int64  `json:"id"`
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	ParentUID string `json:"parentUid,omitempty"`
+	Url       string `json:"url"`
+	HasACL    bool   `json:"hasAcl"`
+	CanSave   bool   `json:"canSave"`
+	CanEdit   bool   `json:"canEdit"`
+	CanAdmin  bool   `json:"canAdmin"`
+	Version   int    `json:"version"`
 }
 
 type Datasource struct {
-	ID                int64            `json:"id"`
-	UID               string           `json:"uid"`
-	OrgID             int64            `json:"orgId"`
-	Name              string           `json:"name"`
-	Type              string           `json:"type"`
-	TypeLogoURL       string           `json:"typeLogoUrl"`
-	Access            string           `json:"access"`
-	URL               string           `json:"url"`
-	Password          string           `json:"password"`
-	User              string           `json:"user"`
-	Database          string           `json:"database"`
-	BasicAuth         bool             `json:"basicAuth"`
-	BasicAuthUser     string           `json:"basicAuthUser"`
-	BasicAuthPassword string           `json:"basicAuthPassword"`
-	WithCredentials   bool             `json:"withCredentials"`
-	IsDefault         bool             `json:"isDefault"`
-	JSONData          *simplejson.Json `json:"jsonData,omitempty"`
-	SecureJsonFields  map[string]bool  `json:"secureJsonFields"`
-	Version           int              `json:"version"`
-	ReadOnly          bool             `json:"readOnly"`
+	ID                int64             `json:"id"`
+	UID               string            `json:"uid"`
+	OrgID             int64             `json:"orgId"`
+	Name              string            `json:"name"`
+	Type              string            `json:"type"`
+	TypeLogoURL       string            `json:"typeLogoUrl"`
+	Access            string            `json:"access"`
+	URL               string            `json:"url"`
+	Password          string            `json:"password"`
+	User              string            `json:"user"`
+	Database          string            `json:"database"`
+	BasicAuth         bool              `json:"basicAuth"`
+	BasicAuthUser     string            `json:"basicAuthUser"`
+	BasicAuthPassword string            `json:"basicAuthPassword"`
+	WithCredentials   bool              `json:"withCredentials"`
+	IsDefault         bool              `json:"isDefault"`
+	JSONData          *simplejson.Json  `json:"jsonData,omitempty"`
+	SecureJsonData    map[string]string `json:"secureJsonData,omitempty"`
+	SecureJsonFields  map[string]bool   `json:"secureJsonFields"`
+	Version           int               `json:"version"`
+	ReadOnly          bool              `json:"readOnly"`
 }
 
 type PromQLQuery struct {
@@ -0,0 +1,135 @@
+package grafsdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const orgIDHeader = "X-Grafana-Org-Id"
+
+type Org struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// WithOrgID sends every request to the given organization instead of the default org of the credentials
+func WithOrgID(orgID int64) Option {
+	return func(c *Client) {
+		c.setOrgID(orgID)
+	}
+}
+
+// WithOrg returns a copy of the client sending every request to the given organization,
+// the copy shares the connections, retry policy and rate limiter of the client
+func (c *Client) WithOrg(orgID int64) *Client {
+	orgClient := *c
+	orgClient.httpClient = c.httpClient.clone()
+	orgClient.setOrgID(orgID)
+	return &orgClient
+}
+
+// OrgID returns the organization set with WithOrgID or WithOrg, 0 means the default org of the credentials
+func (c *Client) OrgID() int64 {
+	return c.orgID
+}
+
+func (c *Client) setOrgID(orgID int64) {
+	c.orgID = orgID
+	if orgID == 0 {
+		c.httpClient.DelHeader(orgIDHeader)
+		return
+	}
+	c.httpClient.SetHeaders(map[string]string{orgIDHeader: strconv.FormatInt(orgID, 10)})
+}
+
+// ListOrgs lists every organization, requires server admin permissions
+func (c *Client) ListOrgs(ctx context.Context) ([]*Org, error) {
+	orgs := []*Org{}
+	perPage := 1000
+	for page := 1; ; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/orgs?perpage=%d&page=%d", c.apiURL, perPage, page), nil)
+		if err != nil {
+			return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+		}
+		pageOrgs := []*Org{}
+		if _, _, err := c.do(ctx, req, &pageOrgs); err != nil {
+			return nil, fmt.Errorf("do: %w", err)
+		}
+		orgs = append(orgs, pageOrgs...)
+		if len(pageOrgs) < perPage {
+			return orgs, nil
+		}
+	}
+}
+
+// ListUserOrgs lists the organizations the signed in user is a member of
+func (c *Client) ListUserOrgs(ctx context.Context) ([]*Org, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/user/orgs", c.apiURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	userOrgs := []*struct {
+		OrgID int64  `json:"orgId"`
+		Name  string `json:"name"`
+	}{}
+	if _, _, err := c.do(ctx, req, &userOrgs); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	orgs := make([]*Org, 0, len(userOrgs))
+	for _, userOrg := range userOrgs {
+		orgs = append(orgs, &Org{ID: userOrg.OrgID, Name: userOrg.Name})
+	}
+	return orgs, nil
+}
+
+// GetCurrentOrg returns the organization requests are sent to
+func (c *Client) GetCurrentOrg(ctx context.Context) (*Org, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/org", c.apiURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	org := Org{}
+	if _, _, err := c.do(ctx, req, &org); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return &org, nil
+}
+
+func (c *Client) GetOrgByName(ctx context.Context, name string) (*Org, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/orgs/name/%s", c.apiURL, url.PathEscape(name)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	org := Org{}
+	if _, _, err := c.do(ctx, req, &org); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return &org, nil
+}
+
+func (c *Client) CreateOrg(ctx context.Context, name string) (*Org, error) {
+	by, err := json.Marshal(Org{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/orgs", c.apiURL), bytes.NewReader(by))
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	orgResp := struct {
+		OrgID int64 `json:"orgId"`
+	}{}
+	if _, _, err := c.do(ctx, req, &orgResp); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return &Org{ID: orgResp.OrgID, Name: name}, nil
+}
@@ -0,0 +1,88 @@
+package grafsdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Permission levels of the folder and dashboard permissions
+const (
+	PermissionView  = 1
+	PermissionEdit  = 2
+	PermissionAdmin = 4
+)
+
+// Permission is a folder or dashboard permission granted to a user, a team or a role
+type Permission struct {
+	UserID     int64  `json:"userId,omitempty"`
+	UserLogin  string `json:"userLogin,omitempty"`
+	TeamID     int64  `json:"teamId,omitempty"`
+	Team       string `json:"team,omitempty"`
+	Role       string `json:"role,omitempty"`
+	Permission int64  `json:"permission"`
+	// Inherited is set for dashboard permissions granted on the folder of the dashboard
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// PermissionItem grants a permission to either a user, a team or a role
+type PermissionItem struct {
+	UserID     int64  `json:"userId,omitempty"`
+	TeamID     int64  `json:"teamId,omitempty"`
+	Role       string `json:"role,omitempty"`
+	Permission int64  `json:"permission"`
+}
+
+func (c *Client) getPermissions(ctx context.Context, path string) ([]*Permission, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s", c.apiURL, path), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	permissions := []*Permission{}
+	if _, _, err := c.do(ctx, req, &permissions); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return permissions, nil
+}
+
+func (c *Client) updatePermissions(ctx context.Context, path string, items []*PermissionItem) error {
+	if items == nil {
+		items = []*PermissionItem{}
+	}
+	by, err := json.Marshal(map[string]interface{}{"items": items})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s%s", c.apiURL, path), bytes.NewReader(by))
+	if err != nil {
+		return fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	if _, _, err := c.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) GetFolderPermissions(ctx context.Context, uid string) ([]*Permission, error) {
+	return c.getPermissions(ctx, fmt.Sprintf("/api/folders/%s/permissions", url.PathEscape(uid)))
+}
+
+// UpdateFolderPermissions replaces every permission of the folder with the items
+func (c *Client) UpdateFolderPermissions(ctx context.Context, uid string, items []*PermissionItem) error {
+	return c.updatePermissions(ctx, fmt.Sprintf("/api/folders/%s/permissions", url.PathEscape(uid)), items)
+}
+
+// GetDashboardPermissions returns the permissions of the dashboard, including the ones inherited from its folder
+func (c *Client) GetDashboardPermissions(ctx context.Context, uid string) ([]*Permission, error) {
+	return c.getPermissions(ctx, fmt.Sprintf("/api/dashboards/uid/%s/permissions", url.PathEscape(uid)))
+}
+
+// UpdateDashboardPermissions replaces the permissions of the dashboard with the items, inherited permissions are kept
+func (c *Client) UpdateDashboardPermissions(ctx context.Context, uid string, items []*PermissionItem) error {
+	return c.updatePermissions(ctx, fmt.Sprintf("/api/dashboards/uid/%s/permissions", url.PathEscape(uid)), items)
+}
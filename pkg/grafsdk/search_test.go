@@ -0,0 +1,69 @@
+package grafsdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchAll(t *testing.T) {
+	total := 2500
+	requests := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		results := []*SearchResult{}
+		for i := (page - 1) * limit; i < page*limit && i < total; i++ {
+			results = append(results, &SearchResult{ID: int64(i), UID: fmt.Sprintf("dash-%d", i), Type: DashHitDB})
+		}
+		json.NewEncoder(w).Encode(results)
+	}))
+	defer srv.Close()
+
+	client := New(srv.URL, "test-key")
+	ctx := context.Background()
+
+	searchResults, err := client.SearchAll(ctx, DashTypeSearchOption(), TagSearchOption("prod", "team-a"))
+	assert.NoError(t, err)
+	assert.Len(t, searchResults, total)
+	assert.Equal(t, "dash-2499", searchResults[total-1].UID)
+	assert.Equal(t, []string{
+		"limit=1000&page=1&tag=prod&tag=team-a&type=dash-db",
+		"limit=1000&page=2&tag=prod&tag=team-a&type=dash-db",
+		"limit=1000&page=3&tag=prod&tag=team-a&type=dash-db",
+	}, requests)
+
+	// custom page size, the last page is full so an extra empty page is requested
+	requests = nil
+	total = 10
+	searchResults, err = client.SearchAll(ctx, LimitSearchOption(5), StarredSearchOption(), DashboardUIDsSearchOption([]string{"a", "b"}), FolderUIDsSearchOption([]string{"c"}))
+	assert.NoError(t, err)
+	assert.Len(t, searchResults, total)
+	assert.Equal(t, []string{
+		"dashboardUIDs=a&dashboardUIDs=b&folderUIDs=c&limit=5&page=1&starred=true",
+		"dashboardUIDs=a&dashboardUIDs=b&folderUIDs=c&limit=5&page=2&starred=true",
+		"dashboardUIDs=a&dashboardUIDs=b&folderUIDs=c&limit=5&page=3&starred=true",
+	}, requests)
+}
+
+func TestSearchAllIgnoredPage(t *testing.T) {
+	// older grafana versions ignore the page parameter and always return the first page
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode([]*SearchResult{{ID: 1, UID: "a"}, {ID: 2, UID: "b"}})
+	}))
+	defer srv.Close()
+
+	searchResults, err := New(srv.URL, "test-key").SearchAll(context.Background(), LimitSearchOption(2))
+	assert.NoError(t, err)
+	assert.Len(t, searchResults, 2)
+	assert.Equal(t, 2, requests)
+}
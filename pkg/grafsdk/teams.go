@@ -0,0 +1,142 @@
+package grafsdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type Team struct {
+	ID          int64  `json:"id"`
+	OrgID       int64  `json:"orgId,omitempty"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	MemberCount int64  `json:"memberCount,omitempty"`
+}
+
+// TeamMember is a user member of a team
+type TeamMember struct {
+	UserID int64  `json:"userId"`
+	TeamID int64  `json:"teamId"`
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+}
+
+// OrgUser is a user of the current organization
+type OrgUser struct {
+	UserID int64  `json:"userId"`
+	Login  string `json:"login"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+}
+
+// SearchTeams walks every page of the teams of the current organization
+func (c *Client) SearchTeams(ctx context.Context) ([]*Team, error) {
+	teams := []*Team{}
+	perPage := 1000
+	for page := 1; ; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/teams/search?perpage=%d&page=%d", c.apiURL, perPage, page), nil)
+		if err != nil {
+			return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+		}
+		resp := struct {
+			TotalCount int     `json:"totalCount"`
+			Teams      []*Team `json:"teams"`
+		}{}
+		if _, _, err := c.do(ctx, req, &resp); err != nil {
+			return nil, fmt.Errorf("do: %w", err)
+		}
+		teams = append(teams, resp.Teams...)
+		if len(resp.Teams) < perPage || len(teams) >= resp.TotalCount {
+			return teams, nil
+		}
+	}
+}
+
+// ListOrgUsers lists the users of the current organization
+func (c *Client) ListOrgUsers(ctx context.Context) ([]*OrgUser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/org/users", c.apiURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	users := []*OrgUser{}
+	if _, _, err := c.do(ctx, req, &users); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return users, nil
+}
+
+func (c *Client) CreateTeam(ctx context.Context, team *Team) (*Team, error) {
+	if team == nil {
+		return nil, fmt.Errorf("missing team")
+	}
+	by, err := json.Marshal(Team{Name: team.Name, Email: team.Email})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/teams", c.apiURL), bytes.NewReader(by))
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	teamResp := struct {
+		TeamID int64 `json:"teamId"`
+	}{}
+	if _, _, err := c.do(ctx, req, &teamResp); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return &Team{ID: teamResp.TeamID, Name: team.Name, Email: team.Email}, nil
+}
+
+func (c *Client) ListTeamMembers(ctx context.Context, teamID int64) ([]*TeamMember, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/teams/%d/members", c.apiURL, teamID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	members := []*TeamMember{}
+	if _, _, err := c.do(ctx, req, &members); err != nil {
+		return nil, fmt.Errorf("do: %w", err)
+	}
+
+	return members, nil
+}
+
+func (c *Client) AddTeamMember(ctx context.Context, teamID int64, userID int64) error {
+	by, err := json.Marshal(map[string]int64{"userId": userID})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/teams/%d/members", c.apiURL, teamID), bytes.NewReader(by))
+	if err != nil {
+		return fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	if _, _, err := c.do(ctx, req, nil); err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+
+	return nil
+}
+
+// AddOrgUser adds an existing grafana user to the current organization with the given role
+func (c *Client) AddOrgUser(ctx context.Context, loginOrEmail string, role string) (int64, error) {
+	by, err := json.Marshal(map[string]string{"loginOrEmail": loginOrEmail, "role": role})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/org/users", c.apiURL), bytes.NewReader(by))
+	if err != nil {
+		return 0, fmt.Errorf("NewRequestWithContext: %w", err)
+	}
+	userResp := struct {
+		UserID int64 `json:"userId"`
+	}{}
+	if _, _, err := c.do(ctx, req, &userResp); err != nil {
+		return 0, fmt.Errorf("do: %w", err)
+	}
+
+	return userResp.UserID, nil
+}
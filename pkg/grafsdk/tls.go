@@ -0,0 +1,58 @@
+package grafsdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSOptions configures how the client verifies the grafana server and authenticates itself over TLS
+type TLSOptions struct {
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system pool
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key used for mutual TLS
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the host name used to verify the server certificate
+	ServerName string
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool
+}
+
+// NewTLSConfig builds a tls.Config from the options, server certificates are verified unless InsecureSkipVerify is set
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CAFile != "" {
+		caBy, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("os.ReadFile: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caBy) {
+			return nil, fmt.Errorf("no certificates found in %q", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate and key must be provided together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls.LoadX509KeyPair: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
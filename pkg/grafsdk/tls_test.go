@@ -0,0 +1,129 @@
+package grafsdk
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	defer srv.Close()
+
+	tempDir := t.TempDir()
+	caFile := filepath.Join(tempDir, "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+
+	// server certificates are verified by default
+	_, err = New(srv.URL, "test-key").ListFolders(ctx)
+	assert.Error(t, err)
+
+	// custom CA bundle
+	tlsConfig, err := NewTLSConfig(TLSOptions{CAFile: caFile})
+	assert.NoError(t, err)
+	_, err = New(srv.URL, "test-key", WithTLSConfig(tlsConfig)).ListFolders(ctx)
+	assert.NoError(t, err)
+
+	// server name override, the httptest certificate is valid for example.com
+	tlsConfig, err = NewTLSConfig(TLSOptions{CAFile: caFile, ServerName: "example.com"})
+	assert.NoError(t, err)
+	_, err = New(srv.URL, "test-key", WithTLSConfig(tlsConfig)).ListFolders(ctx)
+	assert.NoError(t, err)
+	tlsConfig, err = NewTLSConfig(TLSOptions{CAFile: caFile, ServerName: "grafana.internal"})
+	assert.NoError(t, err)
+	_, err = New(srv.URL, "test-key", WithTLSConfig(tlsConfig)).ListFolders(ctx)
+	assert.Error(t, err)
+
+	// explicit opt-in to skip verification
+	tlsConfig, err = NewTLSConfig(TLSOptions{InsecureSkipVerify: true})
+	assert.NoError(t, err)
+	_, err = New(srv.URL, "test-key", WithTLSConfig(tlsConfig)).ListFolders(ctx)
+	assert.NoError(t, err)
+
+	// invalid options
+	_, err = NewTLSConfig(TLSOptions{CAFile: filepath.Join(tempDir, "missing.pem")})
+	assert.Error(t, err)
+	_, err = NewTLSConfig(TLSOptions{CertFile: caFile})
+	assert.Error(t, err)
+}
+
+func TestTLSConfigClientCertificate(t *testing.T) {
+	tempDir := t.TempDir()
+	certFile := filepath.Join(tempDir, "client.pem")
+	keyFile := filepath.Join(tempDir, "client-key.pem")
+	clientCert := writeClientCertificate(t, certFile, keyFile)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	// without a client certificate the handshake fails
+	tlsConfig, err := NewTLSConfig(TLSOptions{InsecureSkipVerify: true})
+	assert.NoError(t, err)
+	_, err = New(srv.URL, "test-key", WithTLSConfig(tlsConfig)).ListFolders(ctx)
+	assert.Error(t, err)
+
+	tlsConfig, err = NewTLSConfig(TLSOptions{InsecureSkipVerify: true, CertFile: certFile, KeyFile: keyFile})
+	assert.NoError(t, err)
+	_, err = New(srv.URL, "test-key", WithTLSConfig(tlsConfig)).ListFolders(ctx)
+	assert.NoError(t, err)
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key as PEM files
+func writeClientCertificate(t *testing.T, certFile string, keyFile string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "grafctl"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certBy, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyBy, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBy}), 0644))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBy}), 0600))
+
+	cert, err := x509.ParseCertificate(certBy)
+	assert.NoError(t, err)
+	return cert
+}